@@ -2,24 +2,53 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/vjranagit/volcano/pkg/metrics"
 	"github.com/vjranagit/volcano/pkg/webhook"
 )
 
 var (
-	port     = flag.Int("port", 8443, "Webhook server port")
-	certFile = flag.String("cert-file", "/etc/webhook/certs/tls.crt", "TLS certificate file")
-	keyFile  = flag.String("key-file", "/etc/webhook/certs/tls.key", "TLS private key file")
-	logLevel = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	port         = flag.Int("port", 8443, "Webhook server port")
+	certFile     = flag.String("cert-file", "/etc/webhook/certs/tls.crt", "TLS certificate file")
+	keyFile      = flag.String("key-file", "/etc/webhook/certs/tls.key", "TLS private key file")
+	logLevel     = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	schemaFile   = flag.String("schema-file", "", "Optional JSON Schema validating admitted JobGroups")
+	configFile   = flag.String("config-file", "", "Optional YAML admission policy config")
+	tokenFile    = flag.String("reload-token-file", "", "File holding the bearer token that enables POST /reload of --config-file")
+	debugPort    = flag.Int("debug-port", 0, "Port serving pprof on 127.0.0.1 over plain HTTP (0 disables)")
+	serveMetrics = flag.Bool("metrics", false, "Serve Prometheus metrics at /metrics on the webhook port")
+	selfSigned   = flag.Bool("self-signed", false, "Serve an in-memory self-signed certificate for localhost instead of --cert-file and --key-file (development only)")
+	drainDelay   = flag.Duration("drain-delay", 5*time.Second, "How long to keep serving after SIGTERM, with readiness failing, before refusing connections")
+	stopTimeout  = flag.Duration("shutdown-timeout", 20*time.Second, "How long shutdown waits for in-flight admission requests after --drain-delay")
+	cacheSize    = flag.Int("response-cache-size", 0, "Cache up to N validation decisions by request UID so API server retries get the same answer (0 disables)")
+	cacheTTL     = flag.Duration("response-cache-ttl", 30*time.Second, "How long --response-cache-size keeps a decision")
+	captureCount = flag.Int("capture-requests", 0, "Keep the last N admission requests in memory and serve them at /debug/requests on --debug-port (0 disables; exposes object contents)")
 )
 
+// envPrefix prefixes the environment variables that fill in flags not set on
+// the command line, e.g. VOLCANO_WEBHOOK_CERT_FILE for --cert-file.
+const envPrefix = "VOLCANO_WEBHOOK_"
+
 func main() {
 	flag.Parse()
+	if err := applyEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *captureCount > 0 && *debugPort <= 0 {
+		fmt.Fprintln(os.Stderr, "--capture-requests requires --debug-port")
+		os.Exit(2)
+	}
 
 	logger := setupLogging(*logLevel)
 	logger.Info("starting volcano admission webhook",
@@ -31,7 +60,68 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	server := webhook.NewServer(*port, *certFile, *keyFile, logger)
+	opts := []webhook.Option{
+		webhook.WithPort(*port),
+		webhook.WithLogger(logger),
+		webhook.WithDebugPort(*debugPort),
+		webhook.WithDrainDelay(*drainDelay),
+		webhook.WithShutdownTimeout(*stopTimeout),
+		webhook.WithRequestCapture(*captureCount, 0),
+		webhook.WithResponseCache(*cacheSize, *cacheTTL),
+	}
+
+	if *selfSigned {
+		cert, err := selfSignedCertificate(flag.CommandLine)
+		if err != nil {
+			logger.Error("failed to set up self-signed certificate", "error", err)
+			os.Exit(1)
+		}
+		logger.Warn("serving a self-signed certificate; do not use in production")
+		opts = append(opts, webhook.WithCertificate(cert))
+	} else {
+		opts = append(opts, webhook.WithTLS(*certFile, *keyFile))
+	}
+
+	if *serveMetrics {
+		opts = append(opts,
+			webhook.WithCollector(metrics.NewCollector(logger)),
+			webhook.WithMetricsEndpoint(),
+		)
+	}
+
+	if *configFile != "" {
+		cfg, err := webhook.LoadConfig(*configFile)
+		if err != nil {
+			logger.Error("failed to load config", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, webhook.WithConfig(cfg))
+
+		if *tokenFile != "" {
+			token, err := os.ReadFile(*tokenFile)
+			if err != nil {
+				logger.Error("failed to read reload token", "error", err)
+				os.Exit(1)
+			}
+			opts = append(opts, webhook.WithConfigReload(*configFile, strings.TrimSpace(string(token))))
+		}
+	}
+
+	if *schemaFile != "" {
+		doc, err := os.ReadFile(*schemaFile)
+		if err != nil {
+			logger.Error("failed to read schema", "error", err)
+			os.Exit(1)
+		}
+		schema, err := webhook.ParseSchema(doc)
+		if err != nil {
+			logger.Error("failed to parse schema", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, webhook.WithSchema(schema))
+	}
+
+	server := webhook.NewServerWithOptions(opts...)
 
 	if err := server.Run(ctx); err != nil {
 		logger.Error("webhook server failed", "error", err)
@@ -41,6 +131,59 @@ func main() {
 	logger.Info("webhook server shutdown complete")
 }
 
+// selfSignedHosts are the names a --self-signed certificate is valid for.
+var selfSignedHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// selfSignedCertificate generates the --self-signed keypair. It refuses to
+// when cert files were also given, so a production flag set cannot silently
+// fall back to an untrusted certificate.
+func selfSignedCertificate(fs *flag.FlagSet) (tls.Certificate, error) {
+	var conflict error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "cert-file" || f.Name == "key-file" {
+			conflict = fmt.Errorf("--self-signed cannot be combined with --%s", f.Name)
+		}
+	})
+	if conflict != nil {
+		return tls.Certificate{}, conflict
+	}
+
+	certPEM, keyPEM, err := webhook.GenerateSelfSignedCert(selfSignedHosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// applyEnv sets each flag in fs that was not given on the command line from
+// its environment variable, if present, so flags take precedence over the
+// environment and the environment over built-in defaults.
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		if value, ok := lookup(name); ok {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// envName returns the environment variable for flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
 func setupLogging(level string) *slog.Logger {
 	var logLevel slog.Level
 	switch level {
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnv_Precedence(t *testing.T) {
+	fs := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	port := fs.Int("port", 8443, "")
+	certFile := fs.String("cert-file", "/default.crt", "")
+	keyFile := fs.String("key-file", "/default.key", "")
+	require.NoError(t, fs.Parse([]string{"--port=9443"}))
+
+	env := map[string]string{
+		"VOLCANO_WEBHOOK_PORT":      "10443",
+		"VOLCANO_WEBHOOK_CERT_FILE": "/env.crt",
+		"VOLCANO_WEBHOOK_UNKNOWN":   "ignored",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	require.NoError(t, applyEnv(fs, lookup))
+
+	assert.Equal(t, 9443, *port, "flag wins over env")
+	assert.Equal(t, "/env.crt", *certFile, "env wins over default")
+	assert.Equal(t, "/default.key", *keyFile, "default used when neither is set")
+}
+
+func TestApplyEnv_InvalidValue(t *testing.T) {
+	fs := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	fs.Int("port", 8443, "")
+	require.NoError(t, fs.Parse(nil))
+
+	err := applyEnv(fs, func(name string) (string, bool) {
+		return "not-a-port", name == "VOLCANO_WEBHOOK_PORT"
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VOLCANO_WEBHOOK_PORT")
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	fs := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	fs.String("cert-file", "/default.crt", "")
+	fs.Bool("self-signed", false, "")
+	require.NoError(t, fs.Parse([]string{"--self-signed"}))
+
+	cert, err := selfSignedCertificate(fs)
+	require.NoError(t, err)
+	require.NotNil(t, cert.Leaf)
+	assert.Contains(t, cert.Leaf.DNSNames, "localhost")
+
+	// Explicit cert files must not be silently replaced.
+	fs = flag.NewFlagSet("webhook", flag.ContinueOnError)
+	fs.String("cert-file", "/default.crt", "")
+	fs.Bool("self-signed", false, "")
+	require.NoError(t, fs.Parse([]string{"--self-signed", "--cert-file=/etc/tls.crt"}))
+
+	_, err = selfSignedCertificate(fs)
+	assert.EqualError(t, err, "--self-signed cannot be combined with --cert-file")
+}
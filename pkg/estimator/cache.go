@@ -0,0 +1,76 @@
+package estimator
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// estimateCache holds recent EstimateResources results per group. Recording
+// a sample bumps the group's generation, which both drops its entry and stops
+// an estimate computed from the older history from being stored. invalidateAll
+// bumps a generation shared by every group, so it also stops estimates in
+// flight for groups with nothing cached yet.
+type estimateCache struct {
+	mu          sync.Mutex
+	entries     map[string]cachedEstimate
+	generations map[string]uint64
+	generation  uint64
+}
+
+type cachedEstimate struct {
+	resources corev1.ResourceList
+	expires   time.Time
+}
+
+func newEstimateCache() *estimateCache {
+	return &estimateCache{
+		entries:     make(map[string]cachedEstimate),
+		generations: make(map[string]uint64),
+	}
+}
+
+// get returns a copy of the estimate cached for key if it has not expired by
+// now, along with the generation to pass to put when it has.
+func (c *estimateCache) get(key string, now time.Time) (corev1.ResourceList, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	generation := c.generation + c.generations[key]
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, generation, false
+	}
+	return entry.resources.DeepCopy(), generation, true
+}
+
+// put caches resources for key until expires, unless key, or the whole
+// cache, was invalidated since generation was read.
+func (c *estimateCache) put(key string, generation uint64, resources corev1.ResourceList, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation+c.generations[key] != generation {
+		return
+	}
+	c.entries[key] = cachedEstimate{resources: resources.DeepCopy(), expires: expires}
+}
+
+// invalidate drops the estimate cached for key.
+func (c *estimateCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	c.generations[key]++
+}
+
+// invalidateAll drops every cached estimate.
+func (c *estimateCache) invalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.generation++
+}
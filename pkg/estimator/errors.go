@@ -0,0 +1,17 @@
+package estimator
+
+import "errors"
+
+// Errors returned by the Estimator, wrapped with the group or resource they
+// concern. Test for them with errors.Is.
+var (
+	// ErrNoHistory reports a group with no recorded samples.
+	ErrNoHistory = errors.New("no history found")
+
+	// ErrInsufficientData reports a group with too few samples for the
+	// requested computation, such as a confidence range from one sample.
+	ErrInsufficientData = errors.New("insufficient data")
+
+	// ErrUnknownResource reports a resource the estimator does not track.
+	ErrUnknownResource = errors.New("unknown resource")
+)
@@ -1,21 +1,55 @@
 package estimator
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/vjranagit/volcano/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-// ResourceUsage tracks resource usage over time.
+// DefaultGPUResourceName is the extended resource GPU estimates are reported
+// under unless the Estimator is configured otherwise.
+const DefaultGPUResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+// Clock tells the time. Tests substitute a fake one to control the
+// time-based behaviour of the estimator.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// ResourceUsage tracks resource usage over time. CPU is in cores, Memory in
+// bytes and GPU in devices.
 type ResourceUsage struct {
 	Timestamp time.Time
 	CPU       float64
 	Memory    float64
 	GPU       float64
+	// Weight is the number of seconds the sample represents. Samples with no
+	// weight count as one second.
+	Weight float64
+	// Replicas is the number of replicas the usage was spread over, or zero
+	// if unknown.
+	Replicas int
+}
+
+// weight returns the effective weight of the sample.
+func (u ResourceUsage) weight() float64 {
+	if u.Weight <= 0 {
+		return 1
+	}
+	return u.Weight
 }
 
 // GroupHistory maintains historical resource usage for a job group.
@@ -25,6 +59,24 @@ type GroupHistory struct {
 	History   []ResourceUsage
 	maxSize   int
 	mu        sync.RWMutex
+
+	// buf backs History with room for twice maxSize samples. History is a
+	// window sliding along it, so appends and evictions reuse it instead of
+	// reallocating; see rewind.
+	buf []ResourceUsage
+
+	// Clock timestamps new samples and anchors the windowed accessors. It
+	// defaults to the system clock.
+	Clock Clock
+
+	// MinInterval, when positive, coalesces a sample arriving sooner than
+	// this after the latest one into it instead of appending.
+	MinInterval time.Duration
+
+	// OutlierMADs, when positive, makes the statistics ignore readings
+	// more than this many median absolute deviations from the median,
+	// judging each resource separately.
+	OutlierMADs float64
 }
 
 // NewGroupHistory creates a new group history tracker.
@@ -34,51 +86,232 @@ func NewGroupHistory(groupName, namespace string, maxSize int) *GroupHistory {
 		Namespace: namespace,
 		History:   make([]ResourceUsage, 0, maxSize),
 		maxSize:   maxSize,
+		Clock:     realClock{},
 	}
 }
 
 // AddUsage records a new resource usage datapoint.
 func (gh *GroupHistory) AddUsage(cpu, memory, gpu float64) {
+	gh.addUsage(ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: 1})
+}
+
+// AddUsageWithDuration records a datapoint representing the given number of
+// seconds of usage.
+func (gh *GroupHistory) AddUsageWithDuration(cpu, memory, gpu, seconds float64) {
+	gh.addUsage(ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: seconds})
+}
+
+// addUsage records a datapoint and returns the stored sample.
+func (gh *GroupHistory) addUsage(usage ResourceUsage) ResourceUsage {
 	gh.mu.Lock()
 	defer gh.mu.Unlock()
 
-	usage := ResourceUsage{
-		Timestamp: time.Now(),
-		CPU:       cpu,
-		Memory:    memory,
-		GPU:       gpu,
+	return gh.appendUsage(usage)
+}
+
+// addSamples records samples in order under a single lock, passing each
+// stored sample to fn when it is not nil.
+func (gh *GroupHistory) addSamples(samples []Sample, fn func(ResourceUsage)) {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	for _, sample := range samples {
+		usage := gh.appendUsage(sample.usage())
+		if fn != nil {
+			fn(usage)
+		}
+	}
+}
+
+// appendUsage stores a datapoint timestamped now, evicting the oldest beyond
+// maxSize. A datapoint within MinInterval of the latest is coalesced into it
+// instead. The caller must hold gh.mu.
+func (gh *GroupHistory) appendUsage(usage ResourceUsage) ResourceUsage {
+	usage.Timestamp = gh.Clock.Now()
+
+	if n := len(gh.History); n > 0 && gh.MinInterval > 0 &&
+		usage.Timestamp.Sub(gh.History[n-1].Timestamp) < gh.MinInterval {
+		gh.History[n-1] = coalesce(gh.History[n-1], usage)
+		return gh.History[n-1]
 	}
 
+	// Keep only maxSize entries (FIFO), making room before appending.
+	if excess := len(gh.History) - gh.maxSize + 1; excess > 0 && gh.maxSize > 0 {
+		gh.History = gh.History[excess:]
+	}
+	if len(gh.History) == cap(gh.History) {
+		gh.rewind()
+	}
 	gh.History = append(gh.History, usage)
-
-	// Keep only maxSize entries (FIFO)
 	if len(gh.History) > gh.maxSize {
-		gh.History = gh.History[1:]
+		gh.History = gh.History[len(gh.History)-gh.maxSize:]
 	}
+
+	return usage
+}
+
+// rewind moves History to the start of buf, allocating buf if it is missing
+// or too small, so the next append has room without reallocating. Fewer than
+// maxSize samples are copied, and with buf twice maxSize long History then
+// reaches its end again only after maxSize more appends, keeping appends
+// amortized O(1). The caller must hold gh.mu.
+func (gh *GroupHistory) rewind() {
+	if size := 2 * gh.maxSize; cap(gh.buf) < size || cap(gh.buf) <= len(gh.History) {
+		gh.buf = make([]ResourceUsage, 0, max(size, len(gh.History)+1))
+	}
+	gh.History = gh.buf[:copy(gh.buf[:len(gh.History)], gh.History)]
+}
+
+// Len returns the number of samples held.
+func (gh *GroupHistory) Len() int {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return len(gh.History)
+}
+
+// latest returns the number of samples and the most recent one. Recording a
+// sample changes at least one of them.
+func (gh *GroupHistory) latest() (int, ResourceUsage) {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	n := len(gh.History)
+	if n == 0 {
+		return 0, ResourceUsage{}
+	}
+	return n, gh.History[n-1]
 }
 
-// GetAverage returns average resource usage.
+// TrimToCount keeps only the n most recent samples, lowering the history's
+// maximum size to n if it was larger, and returns how many samples were
+// dropped. An n below 1 is ignored.
+func (gh *GroupHistory) TrimToCount(n int) int {
+	if n < 1 {
+		return 0
+	}
+
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	if n < gh.maxSize {
+		gh.maxSize = n
+	}
+
+	removed := len(gh.History) - n
+	if removed <= 0 {
+		return 0
+	}
+
+	// Copy so the dropped samples' backing array can be freed; the next
+	// append allocates a buffer sized for the new maximum.
+	gh.History = append([]ResourceUsage(nil), gh.History[removed:]...)
+	gh.buf = nil
+	return removed
+}
+
+// clone returns a copy of gh holding the samples it has now, taken under a
+// single read lock, so several statistics can be computed from one
+// consistent state while samples keep arriving.
+func (gh *GroupHistory) clone() *GroupHistory {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return &GroupHistory{
+		GroupName:   gh.GroupName,
+		Namespace:   gh.Namespace,
+		History:     append([]ResourceUsage(nil), gh.History...),
+		maxSize:     gh.maxSize,
+		Clock:       gh.Clock,
+		MinInterval: gh.MinInterval,
+		OutlierMADs: gh.OutlierMADs,
+	}
+}
+
+// ForEach calls fn with each sample, oldest first, until fn returns false.
+// It iterates under the read lock without copying the history, so fn must
+// not call methods that modify this GroupHistory; doing so deadlocks.
+func (gh *GroupHistory) ForEach(fn func(ResourceUsage) bool) {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	for _, usage := range gh.History {
+		if !fn(usage) {
+			return
+		}
+	}
+}
+
+// GetAverage returns average resource usage, weighting each sample by the
+// duration it represents and ignoring outliers when OutlierMADs is set.
 func (gh *GroupHistory) GetAverage() ResourceUsage {
 	gh.mu.RLock()
 	defer gh.mu.RUnlock()
 
-	if len(gh.History) == 0 {
+	return filtered(gh.History, gh.OutlierMADs, mean)
+}
+
+// mean returns the weighted mean of samples, with Weight holding the total
+// weight.
+func mean(samples []ResourceUsage) ResourceUsage {
+	if len(samples) == 0 {
 		return ResourceUsage{}
 	}
 
-	var totalCPU, totalMem, totalGPU float64
-	for _, usage := range gh.History {
-		totalCPU += usage.CPU
-		totalMem += usage.Memory
-		totalGPU += usage.GPU
+	var total ResourceUsage
+	for _, usage := range samples {
+		w := usage.weight()
+		total.CPU += usage.CPU * w
+		total.Memory += usage.Memory * w
+		total.GPU += usage.GPU * w
+		total.Weight += w
 	}
 
-	count := float64(len(gh.History))
 	return ResourceUsage{
-		CPU:    totalCPU / count,
-		Memory: totalMem / count,
-		GPU:    totalGPU / count,
+		CPU:    total.CPU / total.Weight,
+		Memory: total.Memory / total.Weight,
+		GPU:    total.GPU / total.Weight,
+		Weight: total.Weight,
+	}
+}
+
+// GetAverageForHour returns the average usage of samples taken during the
+// given hour of day (0-23) in UTC, whatever location their timestamps carry.
+// It falls back to the overall average when no sample was taken in that hour.
+func (gh *GroupHistory) GetAverageForHour(hour int) ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	var samples []ResourceUsage
+	for _, usage := range gh.History {
+		if usage.Timestamp.UTC().Hour() == hour {
+			samples = append(samples, usage)
+		}
 	}
+
+	if len(samples) == 0 {
+		samples = gh.History
+	}
+	return filtered(samples, gh.OutlierMADs, mean)
+}
+
+// averageReplicas returns the weighted average replica count of the samples
+// that record one, or 0 if none do.
+func (gh *GroupHistory) averageReplicas() float64 {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	var sum, weights float64
+	for _, usage := range gh.History {
+		if usage.Replicas > 0 {
+			sum += float64(usage.Replicas) * usage.weight()
+			weights += usage.weight()
+		}
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
 }
 
 // GetPeak returns peak resource usage.
@@ -86,72 +319,551 @@ func (gh *GroupHistory) GetPeak() ResourceUsage {
 	gh.mu.RLock()
 	defer gh.mu.RUnlock()
 
-	if len(gh.History) == 0 {
+	return filtered(gh.History, gh.OutlierMADs, peak)
+}
+
+// GetPercentile returns the per-resource p-th percentile, between 0 and 100,
+// of the samples by the nearest-rank method. Sample weights are ignored.
+func (gh *GroupHistory) GetPercentile(p float64) ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return filtered(gh.History, gh.OutlierMADs, func(samples []ResourceUsage) ResourceUsage {
+		return percentile(samples, p)
+	})
+}
+
+// percentile returns the per-resource p-th percentile of samples by the
+// nearest-rank method.
+func percentile(samples []ResourceUsage, p float64) ResourceUsage {
+	n := len(samples)
+	if n == 0 {
 		return ResourceUsage{}
 	}
 
-	peak := gh.History[0]
+	rank := int(math.Ceil(math.Max(0, math.Min(p, 100)) / 100 * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	values := make([]float64, n)
+	nth := func(value func(ResourceUsage) float64) float64 {
+		for i, usage := range samples {
+			values[i] = value(usage)
+		}
+		sort.Float64s(values)
+		return values[rank-1]
+	}
+
+	return ResourceUsage{
+		CPU:    nth(resourceValues[0]),
+		Memory: nth(resourceValues[1]),
+		GPU:    nth(resourceValues[2]),
+	}
+}
+
+// GetDecayedPeak returns the per-resource peak with each sample discounted by
+// half for every halfLife of age, so a recent moderate peak can overtake an
+// old extreme one without waiting for it to be evicted. A non-positive
+// halfLife returns GetPeak.
+func (gh *GroupHistory) GetDecayedPeak(halfLife time.Duration) ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	if halfLife <= 0 {
+		return filtered(gh.History, gh.OutlierMADs, peak)
+	}
+
+	now := gh.Clock.Now()
+	return filtered(gh.History, gh.OutlierMADs, func(samples []ResourceUsage) ResourceUsage {
+		var highest ResourceUsage
+		for _, usage := range samples {
+			age := now.Sub(usage.Timestamp)
+			if age < 0 {
+				age = 0
+			}
+			factor := math.Exp2(-age.Seconds() / halfLife.Seconds())
+
+			highest.CPU = math.Max(highest.CPU, usage.CPU*factor)
+			highest.Memory = math.Max(highest.Memory, usage.Memory*factor)
+			highest.GPU = math.Max(highest.GPU, usage.GPU*factor)
+		}
+		return highest
+	})
+}
+
+// GetAverageSince returns the average usage of samples taken within d of now.
+// It returns the most recent sample when none is that recent.
+func (gh *GroupHistory) GetAverageSince(d time.Duration) ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return filtered(gh.since(d), gh.OutlierMADs, mean)
+}
+
+// GetPeakSince returns the peak usage of samples taken within d of now, so an
+// old one-off spike stops inflating estimates once it leaves the window. It
+// returns the most recent sample when none is that recent.
+func (gh *GroupHistory) GetPeakSince(d time.Duration) ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return filtered(gh.since(d), gh.OutlierMADs, peak)
+}
+
+// since returns the samples taken within d of now, or just the most recent
+// sample when there are none. The caller must hold gh.mu.
+func (gh *GroupHistory) since(d time.Duration) []ResourceUsage {
+	if len(gh.History) == 0 {
+		return nil
+	}
+
+	cutoff := gh.Clock.Now().Add(-d)
+	var samples []ResourceUsage
 	for _, usage := range gh.History {
-		if usage.CPU > peak.CPU {
-			peak.CPU = usage.CPU
+		if !usage.Timestamp.Before(cutoff) {
+			samples = append(samples, usage)
+		}
+	}
+
+	if len(samples) == 0 {
+		return gh.History[len(gh.History)-1:]
+	}
+	return samples
+}
+
+// peak returns the per-resource maximum of samples.
+func peak(samples []ResourceUsage) ResourceUsage {
+	if len(samples) == 0 {
+		return ResourceUsage{}
+	}
+
+	highest := samples[0]
+	for _, usage := range samples {
+		if usage.CPU > highest.CPU {
+			highest.CPU = usage.CPU
 		}
-		if usage.Memory > peak.Memory {
-			peak.Memory = usage.Memory
+		if usage.Memory > highest.Memory {
+			highest.Memory = usage.Memory
 		}
-		if usage.GPU > peak.GPU {
-			peak.GPU = usage.GPU
+		if usage.GPU > highest.GPU {
+			highest.GPU = usage.GPU
 		}
 	}
 
-	return peak
+	return highest
+}
+
+// GetStdDev returns the population standard deviation of each resource,
+// using the same sample weights as GetAverage.
+func (gh *GroupHistory) GetStdDev() ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	return filtered(gh.History, gh.OutlierMADs, stddev)
+}
+
+// stddev returns the weighted population standard deviation of each resource
+// of samples.
+func stddev(samples []ResourceUsage) ResourceUsage {
+	if len(samples) == 0 {
+		return ResourceUsage{}
+	}
+
+	avg := mean(samples)
+
+	var sqCPU, sqMem, sqGPU float64
+	for _, usage := range samples {
+		w := usage.weight()
+		sqCPU += w * (usage.CPU - avg.CPU) * (usage.CPU - avg.CPU)
+		sqMem += w * (usage.Memory - avg.Memory) * (usage.Memory - avg.Memory)
+		sqGPU += w * (usage.GPU - avg.GPU) * (usage.GPU - avg.GPU)
+	}
+
+	return ResourceUsage{
+		CPU:    math.Sqrt(sqCPU / avg.Weight),
+		Memory: math.Sqrt(sqMem / avg.Weight),
+		GPU:    math.Sqrt(sqGPU / avg.Weight),
+	}
 }
 
+// GetRate returns the per-second change of each resource between the oldest
+// and newest samples. It returns zeros when there are fewer than two samples
+// or they share a timestamp.
+func (gh *GroupHistory) GetRate() ResourceUsage {
+	gh.mu.RLock()
+	defer gh.mu.RUnlock()
+
+	if len(gh.History) < 2 {
+		return ResourceUsage{}
+	}
+
+	first := gh.History[0]
+	last := gh.History[len(gh.History)-1]
+
+	seconds := last.Timestamp.Sub(first.Timestamp).Seconds()
+	if seconds == 0 {
+		return ResourceUsage{}
+	}
+
+	return ResourceUsage{
+		CPU:    (last.CPU - first.CPU) / seconds,
+		Memory: (last.Memory - first.Memory) / seconds,
+		GPU:    (last.GPU - first.GPU) / seconds,
+	}
+}
+
+// RecordFunc is called with each usage sample stored by the Estimator.
+type RecordFunc func(namespace, groupName string, usage ResourceUsage)
+
 // Estimator predicts resource needs based on historical patterns.
 type Estimator struct {
+	// GPUResourceName is the resource GPU estimates are reported under,
+	// e.g. "amd.com/gpu". It should be set before the Estimator is used.
+	GPUResourceName corev1.ResourceName
+
+	// FractionalGPU reports GPU estimates in thousandths of a device, e.g.
+	// 250m for a quarter of a MIG-partitioned or time-sliced GPU, instead of
+	// truncating them to whole GPUs. Pair it with a GPUResourceName the
+	// device plugin advertises fractionally. Off by default.
+	FractionalGPU bool
+
+	// LimitFactor scales peak usage into the limits returned by
+	// EstimateRequestsAndLimits. Values below 1 are treated as 1 so limits
+	// never fall under requests.
+	LimitFactor float64
+
+	// MinSamples is the number of samples a group needs before
+	// EstimateResourcesForAll reports an estimate for it. Zero reports every
+	// group.
+	MinSamples int
+
+	// Collector, when set, receives the number of samples held per group.
+	// It should be set before the Estimator is used.
+	Collector *metrics.Collector
+
+	// CacheTTL, when positive, lets EstimateResources return a group's
+	// previous estimate for this long instead of recomputing it. Recording a
+	// sample for the group discards its cached estimate. Off by default.
+	CacheTTL time.Duration
+
+	// Smoothing, between 0 and 1, blends each EstimateResources result with
+	// the previous one for the group: the emitted estimate is Smoothing times
+	// the previous plus 1-Smoothing times the new computation. It damps
+	// oscillation on noisy histories. The estimate only moves when the
+	// group's history has changed since the previous one. Zero, the
+	// default, disables it.
+	Smoothing float64
+
+	// Clock timestamps samples and ages history. It defaults to the system
+	// clock and should be set before the Estimator is used.
+	Clock Clock
+
+	// OutlierMADs, when positive, makes every statistic estimates are built
+	// from ignore readings more than this many median absolute deviations
+	// from the group's median, judged per resource, so a metrics glitch
+	// cannot skew estimates. Around 3 to 5 is typical. Zero, the default,
+	// keeps every sample. It should be set before the Estimator is used.
+	OutlierMADs float64
+
+	// MinInterval, when positive, coalesces a sample for a group that
+	// arrives sooner than this after the group's previous one into it,
+	// keeping the larger of each resource, so collectors recording in a
+	// tight loop do not flood the history. Zero, the default, appends every
+	// sample. It should be set before the Estimator is used.
+	MinInterval time.Duration
+
+	strategyName string
+	strategy     Strategy
+
+	// resourceStrategies override strategy per resource, and
+	// resourceStrategyNames name them; see SetResourceStrategy.
+	resourceStrategies    map[corev1.ResourceName]Strategy
+	resourceStrategyNames map[corev1.ResourceName]string
+
+	cache     *estimateCache
+	smoothed  map[string]smoothedEstimate // last smoothed estimate per group
+	smoothMu  sync.Mutex
 	histories map[string]*GroupHistory // key: namespace/groupName
 	mu        sync.RWMutex
 	logger    *slog.Logger
 	maxSize   int
+	onRecord  []RecordFunc
 }
 
-// NewEstimator creates a new resource estimator.
+// NewEstimator creates a new resource estimator using DefaultStrategy.
 func NewEstimator(maxHistorySize int, logger *slog.Logger) *Estimator {
+	e, _ := NewEstimatorWithStrategy(maxHistorySize, logger, DefaultStrategy)
+	return e
+}
+
+// NewEstimatorWithStrategy creates a new resource estimator that predicts
+// with the registered strategy of the given name.
+func NewEstimatorWithStrategy(maxHistorySize int, logger *slog.Logger, strategy string) (*Estimator, error) {
+	s, err := lookupStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
 	if logger == nil {
 		logger = slog.Default()
 	}
 
 	return &Estimator{
-		histories: make(map[string]*GroupHistory),
-		logger:    logger,
-		maxSize:   maxHistorySize,
+		GPUResourceName: DefaultGPUResourceName,
+		LimitFactor:     1,
+		Clock:           realClock{},
+		strategyName:    strategy,
+		strategy:        s,
+		cache:           newEstimateCache(),
+		smoothed:        make(map[string]smoothedEstimate),
+		histories:       make(map[string]*GroupHistory),
+		logger:          logger,
+		maxSize:         maxHistorySize,
+	}, nil
+}
+
+// StrategyName returns the name of the strategy the estimator predicts with.
+func (e *Estimator) StrategyName() string {
+	return e.strategyName
+}
+
+// SetResourceStrategy predicts resource with the registered strategy of the
+// given name instead of the estimator's strategy, e.g. "p95" for memory,
+// whose overruns are fatal, and "average" for CPU, which is only throttled.
+// resource must be cpu, memory or the GPUResourceName; other resources
+// return ErrUnknownResource. It should be called before the Estimator is
+// used.
+func (e *Estimator) SetResourceStrategy(resource corev1.ResourceName, strategy string) error {
+	switch resource {
+	case corev1.ResourceCPU, corev1.ResourceMemory, e.GPUResourceName:
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownResource, resource)
+	}
+
+	s, err := lookupStrategy(strategy)
+	if err != nil {
+		return err
+	}
+
+	if e.resourceStrategies == nil {
+		e.resourceStrategies = make(map[corev1.ResourceName]Strategy)
+		e.resourceStrategyNames = make(map[corev1.ResourceName]string)
 	}
+	e.resourceStrategies[resource] = s
+	e.resourceStrategyNames[resource] = strategy
+	return nil
 }
 
-// RecordUsage records resource usage for a group.
+// estimate runs the estimator's strategy over history, taking each resource
+// set with SetResourceStrategy from its own strategy instead.
+func (e *Estimator) estimate(history *GroupHistory) ResourceUsage {
+	estimated := e.strategy.Estimate(history)
+	if s, ok := e.resourceStrategies[corev1.ResourceCPU]; ok {
+		estimated.CPU = s.Estimate(history).CPU
+	}
+	if s, ok := e.resourceStrategies[corev1.ResourceMemory]; ok {
+		estimated.Memory = s.Estimate(history).Memory
+	}
+	if s, ok := e.resourceStrategies[e.GPUResourceName]; ok {
+		estimated.GPU = s.Estimate(history).GPU
+	}
+	return estimated
+}
+
+// strategyNames returns the name of the strategy estimating each resource.
+func (e *Estimator) strategyNames() map[corev1.ResourceName]string {
+	names := make(map[corev1.ResourceName]string, 3)
+	for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, e.GPUResourceName} {
+		names[resource] = e.strategyName
+		if name, ok := e.resourceStrategyNames[resource]; ok {
+			names[resource] = name
+		}
+	}
+	return names
+}
+
+// newHistory creates a history for a group that shares the estimator clock,
+// minimum sample interval and outlier threshold.
+func (e *Estimator) newHistory(groupName, namespace string) *GroupHistory {
+	history := NewGroupHistory(groupName, namespace, e.maxSize)
+	history.Clock = e.Clock
+	history.MinInterval = e.MinInterval
+	history.OutlierMADs = e.OutlierMADs
+	return history
+}
+
+// OnRecord registers fn to be called after each sample is stored. Callbacks
+// run synchronously on the recording goroutine, outside the estimator locks.
+func (e *Estimator) OnRecord(fn RecordFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onRecord = append(e.onRecord, fn)
+}
+
+// RecordUsage records resource usage for a group, with cpu in cores, memory
+// in bytes and gpu in devices. It is the same as RecordUsageCores; callers
+// holding millicores, as metrics-server reports them, should use
+// RecordUsageMillicores instead.
 func (e *Estimator) RecordUsage(namespace, groupName string, cpu, memory, gpu float64) {
+	e.record(namespace, groupName, ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: 1})
+}
+
+// RecordUsageCores records resource usage for a group with cpu in cores,
+// e.g. 1.5, memory in bytes and gpu in devices.
+func (e *Estimator) RecordUsageCores(namespace, groupName string, cores, memory, gpu float64) {
+	e.RecordUsage(namespace, groupName, cores, memory, gpu)
+}
+
+// RecordUsageMillicores records resource usage for a group with cpu in
+// millicores, e.g. 1500 for one and a half cores, memory in bytes and gpu in
+// devices.
+func (e *Estimator) RecordUsageMillicores(namespace, groupName string, millicores, memory, gpu float64) {
+	e.RecordUsage(namespace, groupName, millicores/1000, memory, gpu)
+}
+
+// RecordUsageWithReplicas records aggregate resource usage for a group that
+// was running replicas replicas, so EstimatePerReplica can scale it.
+func (e *Estimator) RecordUsageWithReplicas(namespace, groupName string, cpu, memory, gpu float64, replicas int) {
+	e.record(namespace, groupName, ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: 1, Replicas: replicas})
+}
+
+// RecordUsageContext is RecordUsage that returns ctx.Err() without recording
+// once ctx is cancelled, so shutdown is not held up by estimator work.
+func (e *Estimator) RecordUsageContext(ctx context.Context, namespace, groupName string, cpu, memory, gpu float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e.record(namespace, groupName, ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: 1})
+	return nil
+}
+
+// RecordUsageWithDuration records resource usage for a group that was
+// sustained for the given number of seconds. Averages weight each sample by
+// its duration, so a short spike counts less than a long steady period.
+func (e *Estimator) RecordUsageWithDuration(namespace, groupName string, cpu, memory, gpu, seconds float64) {
+	e.record(namespace, groupName, ResourceUsage{CPU: cpu, Memory: memory, GPU: gpu, Weight: seconds})
+}
+
+// Sample is one usage datapoint passed to RecordUsageBatch.
+type Sample struct {
+	Namespace string
+	GroupName string
+	CPU       float64
+	Memory    float64
+	GPU       float64
+
+	// Seconds is how long the usage was sustained, as for
+	// RecordUsageWithDuration. Zero records a plain RecordUsage sample.
+	Seconds float64
+
+	// Replicas is the number of replicas the usage was spread over, as for
+	// RecordUsageWithReplicas. Zero means unknown.
+	Replicas int
+}
+
+// usage returns the sample as it is stored.
+func (s Sample) usage() ResourceUsage {
+	weight := s.Seconds
+	if weight <= 0 {
+		weight = 1
+	}
+	return ResourceUsage{CPU: s.CPU, Memory: s.Memory, GPU: s.GPU, Weight: weight, Replicas: s.Replicas}
+}
+
+// RecordUsageBatch records samples as RecordUsage and RecordUsageWithDuration
+// would, in order per group, but takes the estimator lock once for the batch
+// and each group's lock once, which cuts contention for collectors pushing
+// many samples at a time.
+func (e *Estimator) RecordUsageBatch(samples []Sample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	var keys []string
+	byKey := make(map[string][]Sample)
+	for _, sample := range samples {
+		key := fmt.Sprintf("%s/%s", sample.Namespace, sample.GroupName)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], sample)
+	}
+
+	histories := make([]*GroupHistory, len(keys))
+	e.mu.Lock()
+	for i, key := range keys {
+		history, exists := e.histories[key]
+		if !exists {
+			first := byKey[key][0]
+			history = e.newHistory(first.GroupName, first.Namespace)
+			e.histories[key] = history
+		}
+		histories[i] = history
+	}
+	callbacks := e.onRecord
+	e.mu.Unlock()
+
+	for i, key := range keys {
+		history := histories[i]
+
+		// Callbacks run after the group's lock is released.
+		var stored []ResourceUsage
+		var keep func(ResourceUsage)
+		if len(callbacks) > 0 {
+			keep = func(usage ResourceUsage) { stored = append(stored, usage) }
+		}
+		history.addSamples(byKey[key], keep)
+
+		e.cache.invalidate(key)
+		e.reportSamples(history)
+		for _, usage := range stored {
+			for _, fn := range callbacks {
+				fn(history.Namespace, history.GroupName, usage)
+			}
+		}
+	}
+
+	e.logger.Debug("recorded resource usage batch",
+		"samples", len(samples),
+		"groups", len(keys),
+	)
+}
+
+func (e *Estimator) record(namespace, groupName string, usage ResourceUsage) {
 	key := fmt.Sprintf("%s/%s", namespace, groupName)
 
 	e.mu.Lock()
 	history, exists := e.histories[key]
 	if !exists {
-		history = NewGroupHistory(groupName, namespace, e.maxSize)
+		history = e.newHistory(groupName, namespace)
 		e.histories[key] = history
 	}
+	callbacks := e.onRecord
 	e.mu.Unlock()
 
-	history.AddUsage(cpu, memory, gpu)
+	usage = history.addUsage(usage)
+	e.cache.invalidate(key)
+	e.reportSamples(history)
+	for _, fn := range callbacks {
+		fn(namespace, groupName, usage)
+	}
 
 	e.logger.Debug("recorded resource usage",
 		"namespace", namespace,
 		"group", groupName,
-		"cpu", cpu,
-		"memory", memory,
-		"gpu", gpu,
+		"cpu", usage.CPU,
+		"memory", usage.Memory,
+		"gpu", usage.GPU,
+		"weight", usage.Weight,
+		"replicas", usage.Replicas,
 	)
 }
 
-// EstimateResources predicts resource needs for a group.
-// Uses weighted average: 70% average + 30% peak for safety margin.
+// EstimateResources predicts resource needs for a group with the estimator's
+// strategy. The default weights 70% average + 30% peak for safety margin.
 func (e *Estimator) EstimateResources(namespace, groupName string) (corev1.ResourceList, error) {
 	key := fmt.Sprintf("%s/%s", namespace, groupName)
 
@@ -160,35 +872,390 @@ func (e *Estimator) EstimateResources(namespace, groupName string) (corev1.Resou
 	e.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("no history found for %s", key)
+		return nil, fmt.Errorf("%w for %s", ErrNoHistory, key)
+	}
+
+	resources, estimated, cached := e.estimateGroup(key, history)
+	if cached {
+		return resources, nil
+	}
+
+	e.logger.Info("estimated resources",
+		"namespace", namespace,
+		"group", groupName,
+		"cpu", estimated.CPU,
+		"memory", estimated.Memory,
+		"gpu", estimated.GPU,
+	)
+
+	return resources, nil
+}
+
+// estimateGroup returns the estimate for the group under key: the cached
+// one while it is fresh, otherwise a smoothed estimate computed from history
+// and cached. cached reports which; estimated is only set when computed.
+func (e *Estimator) estimateGroup(key string, history *GroupHistory) (resources corev1.ResourceList, estimated ResourceUsage, cached bool) {
+	var generation uint64
+	if e.CacheTTL > 0 {
+		var ok bool
+		if resources, generation, ok = e.cache.get(key, e.Clock.Now()); ok {
+			return resources, ResourceUsage{}, true
+		}
+	}
+
+	estimated = e.smooth(key, history, e.estimate(history))
+	resources = e.resourceList(estimated)
+
+	if e.CacheTTL > 0 {
+		e.cache.put(key, generation, resources, e.Clock.Now().Add(e.CacheTTL))
+	}
+	return resources, estimated, false
+}
+
+// EstimateResourcesWithFloor is EstimateResources raised, per resource, to at
+// least floor, e.g. the JobGroup's own requests, so a job that has not ramped
+// up yet is not starved. Resources in floor the estimate lacks are returned
+// at the floor value.
+func (e *Estimator) EstimateResourcesWithFloor(namespace, groupName string, floor corev1.ResourceList) (corev1.ResourceList, error) {
+	estimated, err := e.EstimateResources(namespace, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The estimate may be shared with the cache, so raise a copy.
+	resources := estimated.DeepCopy()
+	raiseTo(resources, floor)
+	return resources, nil
+}
+
+// raiseTo raises each resource in resources to at least its value in floor,
+// adding those it lacks.
+func raiseTo(resources, floor corev1.ResourceList) {
+	for name, minimum := range floor {
+		if current, ok := resources[name]; !ok || current.Cmp(minimum) < 0 {
+			resources[name] = minimum.DeepCopy()
+		}
+	}
+}
+
+// EstimateDetails describes how an estimate was produced, for debugging
+// mispredictions.
+type EstimateDetails struct {
+	Strategy string        // name of the estimator's strategy
+	Samples  int           // samples in the group history
+	Average  ResourceUsage // average usage over the history
+	Peak     ResourceUsage // peak usage over the history
+
+	// Strategies names the strategy that estimated each resource: Strategy,
+	// unless overridden with SetResourceStrategy.
+	Strategies map[corev1.ResourceName]string
+}
+
+// EstimateDetailed is EstimateResources that also reports the strategies,
+// sample count and average and peak usage behind the estimate. The estimate
+// and its details are computed from one snapshot of the history, so a
+// concurrent RecordUsage cannot make them disagree; for the same reason the
+// estimate is always computed afresh rather than read from the cache.
+func (e *Estimator) EstimateDetailed(namespace, groupName string) (corev1.ResourceList, EstimateDetails, error) {
+	key := fmt.Sprintf("%s/%s", namespace, groupName)
+
+	e.mu.RLock()
+	history, exists := e.histories[key]
+	e.mu.RUnlock()
+
+	if !exists {
+		return nil, EstimateDetails{}, fmt.Errorf("%w for %s", ErrNoHistory, key)
+	}
+
+	snapshot := history.clone()
+	details := EstimateDetails{
+		Strategy:   e.strategyName,
+		Samples:    snapshot.Len(),
+		Average:    snapshot.GetAverage(),
+		Peak:       snapshot.GetPeak(),
+		Strategies: e.strategyNames(),
+	}
+
+	estimated := e.smooth(key, snapshot, e.estimate(snapshot))
+	return e.resourceList(estimated), details, nil
+}
+
+// smoothedEstimate is a smoothed estimate with the state of the history it
+// was computed from.
+type smoothedEstimate struct {
+	usage   ResourceUsage
+	samples int
+	latest  ResourceUsage
+}
+
+// smooth blends estimated, computed from history, with the previous estimate
+// for key according to Smoothing and remembers the result. The first estimate
+// passes through. Blending only happens once history has changed, so repeated
+// reads without new samples return the same estimate instead of creeping
+// toward the raw one.
+func (e *Estimator) smooth(key string, history *GroupHistory, estimated ResourceUsage) ResourceUsage {
+	if e.Smoothing <= 0 || e.Smoothing >= 1 {
+		return estimated
+	}
+
+	samples, latest := history.latest()
+
+	e.smoothMu.Lock()
+	defer e.smoothMu.Unlock()
+
+	if previous, ok := e.smoothed[key]; ok {
+		if previous.samples == samples && previous.latest == latest {
+			return previous.usage
+		}
+		keep := e.Smoothing
+		estimated = ResourceUsage{
+			CPU:    keep*previous.usage.CPU + (1-keep)*estimated.CPU,
+			Memory: keep*previous.usage.Memory + (1-keep)*estimated.Memory,
+			GPU:    keep*previous.usage.GPU + (1-keep)*estimated.GPU,
+		}
+	}
+	e.smoothed[key] = smoothedEstimate{usage: estimated, samples: samples, latest: latest}
+	return estimated
+}
+
+// forgetSmoothing drops the smoothing state of a group that is no longer
+// tracked.
+func (e *Estimator) forgetSmoothing(key string) {
+	e.smoothMu.Lock()
+	defer e.smoothMu.Unlock()
+
+	delete(e.smoothed, key)
+}
+
+// EstimateResourcesContext is EstimateResources that returns ctx.Err()
+// without estimating once ctx is cancelled.
+func (e *Estimator) EstimateResourcesContext(ctx context.Context, namespace, groupName string) (corev1.ResourceList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return e.EstimateResources(namespace, groupName)
+}
+
+// EstimateRequestsAndLimits predicts pod requests and limits for a group.
+// Requests use the same strategy as EstimateResources; limits use peak usage
+// scaled by LimitFactor.
+func (e *Estimator) EstimateRequestsAndLimits(namespace, groupName string) (requests, limits corev1.ResourceList, err error) {
+	history, exists := e.GetHistory(namespace, groupName)
+	if !exists {
+		return nil, nil, fmt.Errorf("%w for %s/%s", ErrNoHistory, namespace, groupName)
 	}
 
-	avg := history.GetAverage()
 	peak := history.GetPeak()
 
-	// Weighted estimation: 70% avg + 30% peak
-	estimatedCPU := avg.CPU*0.7 + peak.CPU*0.3
-	estimatedMem := avg.Memory*0.7 + peak.Memory*0.3
-	estimatedGPU := avg.GPU*0.7 + peak.GPU*0.3
+	factor := math.Max(e.LimitFactor, 1)
+	limit := ResourceUsage{
+		CPU:    peak.CPU * factor,
+		Memory: peak.Memory * factor,
+		GPU:    peak.GPU * factor,
+	}
 
-	resources := corev1.ResourceList{
-		corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(estimatedCPU*1000), resource.DecimalSI),
-		corev1.ResourceMemory: *resource.NewQuantity(int64(estimatedMem), resource.BinarySI),
+	return e.resourceList(e.estimate(history)), e.resourceList(limit), nil
+}
+
+// EstimatePerReplica scales the group's aggregate estimate to replicas
+// replicas: it divides the strategy's estimate by the group's historical
+// replica count, the weighted average over samples recorded with
+// RecordUsageWithReplicas, and multiplies by replicas. It returns nil when the
+// group has no history, no sample carries a replica count, or replicas is
+// below 1. Smoothing and the estimate cache are not applied.
+func (e *Estimator) EstimatePerReplica(namespace, groupName string, replicas int) corev1.ResourceList {
+	history, exists := e.GetHistory(namespace, groupName)
+	if !exists || replicas < 1 {
+		return nil
 	}
 
-	if estimatedGPU > 0 {
-		resources["nvidia.com/gpu"] = *resource.NewQuantity(int64(estimatedGPU), resource.DecimalSI)
+	historical := history.averageReplicas()
+	if historical == 0 {
+		return nil
 	}
 
-	e.logger.Info("estimated resources",
+	estimated := e.estimate(history)
+	scale := float64(replicas) / historical
+	return e.resourceList(ResourceUsage{
+		CPU:    estimated.CPU * scale,
+		Memory: estimated.Memory * scale,
+		GPU:    estimated.GPU * scale,
+	})
+}
+
+// EstimateResourcesAt predicts resource needs for a group at the time of day
+// of at. It blends the average of samples taken in the same hour with the
+// overall peak, so workloads with daily cycles are priced for the hour they
+// run in. Hours are compared in UTC, so at may be in any location. Use
+// time.Now() for the current hour.
+func (e *Estimator) EstimateResourcesAt(namespace, groupName string, at time.Time) (corev1.ResourceList, error) {
+	history, exists := e.GetHistory(namespace, groupName)
+	if !exists {
+		return nil, fmt.Errorf("%w for %s/%s", ErrNoHistory, namespace, groupName)
+	}
+
+	hour := at.UTC().Hour()
+	estimated := weightedBlend(history.GetAverageForHour(hour), history.GetPeak())
+
+	e.logger.Info("estimated resources for hour",
 		"namespace", namespace,
 		"group", groupName,
-		"cpu", estimatedCPU,
-		"memory", estimatedMem,
-		"gpu", estimatedGPU,
+		"hour", hour,
+		"cpu", estimated.CPU,
+		"memory", estimated.Memory,
+		"gpu", estimated.GPU,
 	)
 
-	return resources, nil
+	return e.resourceList(estimated), nil
+}
+
+// weightedBlend combines average and peak usage: 70% avg + 30% peak for a
+// safety margin.
+func weightedBlend(avg, peak ResourceUsage) ResourceUsage {
+	return ResourceUsage{
+		CPU:    avg.CPU*0.7 + peak.CPU*0.3,
+		Memory: avg.Memory*0.7 + peak.Memory*0.3,
+		GPU:    avg.GPU*0.7 + peak.GPU*0.3,
+	}
+}
+
+// ConfidenceEstimate is a resource estimate with a confidence range.
+type ConfidenceEstimate struct {
+	// Estimate is the point estimate, as returned by EstimateResources.
+	Estimate corev1.ResourceList
+	// Lower and Upper bound the range mean ± z*stddev per resource.
+	Lower corev1.ResourceList
+	Upper corev1.ResourceList
+}
+
+// EstimateResourcesWithConfidence returns the point estimate for a group
+// together with lower and upper bounds of mean ± z*stddev per resource. The
+// lower bound is clamped at zero. A z of 1.96 gives a ~95% interval for
+// normally distributed usage. A group with fewer than two samples has no
+// spread to measure and gets ErrInsufficientData.
+func (e *Estimator) EstimateResourcesWithConfidence(namespace, groupName string, z float64) (*ConfidenceEstimate, error) {
+	estimate, err := e.EstimateResources(namespace, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	history, _ := e.GetHistory(namespace, groupName)
+	if n := history.Len(); n < 2 {
+		return nil, fmt.Errorf("%w for %s/%s: a confidence range needs at least 2 samples, have %d",
+			ErrInsufficientData, namespace, groupName, n)
+	}
+	avg := history.GetAverage()
+	stddev := history.GetStdDev()
+
+	lower := ResourceUsage{
+		CPU:    math.Max(0, avg.CPU-z*stddev.CPU),
+		Memory: math.Max(0, avg.Memory-z*stddev.Memory),
+		GPU:    math.Max(0, avg.GPU-z*stddev.GPU),
+	}
+	upper := ResourceUsage{
+		CPU:    avg.CPU + z*stddev.CPU,
+		Memory: avg.Memory + z*stddev.Memory,
+		GPU:    avg.GPU + z*stddev.GPU,
+	}
+
+	return &ConfidenceEstimate{
+		Estimate: estimate,
+		Lower:    e.resourceList(lower),
+		Upper:    e.resourceList(upper),
+	}, nil
+}
+
+// resourceList converts usage in cores, bytes and devices to a ResourceList,
+// rounding CPU, and fractional GPUs, to the nearest thousandth so float error
+// in the strategy arithmetic cannot shave one off. GPUs are only included
+// when non-zero.
+func (e *Estimator) resourceList(usage ResourceUsage) corev1.ResourceList {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(math.Round(usage.CPU*1000)), resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(int64(usage.Memory), resource.BinarySI),
+	}
+
+	if usage.GPU > 0 {
+		if e.FractionalGPU {
+			resources[e.GPUResourceName] = *resource.NewMilliQuantity(int64(math.Round(usage.GPU*1000)), resource.DecimalSI)
+		} else {
+			resources[e.GPUResourceName] = *resource.NewQuantity(int64(usage.GPU), resource.DecimalSI)
+		}
+	}
+
+	return resources
+}
+
+// reportSamples publishes the sample count of history to the Collector.
+func (e *Estimator) reportSamples(history *GroupHistory) {
+	if e.Collector != nil {
+		e.Collector.SetEstimatorGroupSamples(history.Namespace, history.GroupName, history.Len())
+	}
+}
+
+// forgetSamples removes the sample count of a dropped history from the
+// Collector.
+func (e *Estimator) forgetSamples(history *GroupHistory) {
+	if e.Collector != nil {
+		e.Collector.DeleteEstimatorGroupSamples(history.Namespace, history.GroupName)
+	}
+}
+
+// GroupEstimate is the current estimate for one group.
+type GroupEstimate struct {
+	Namespace string `json:"namespace"`
+	Group     string `json:"group"`
+
+	// Resources is the estimate, omitted while the group is below
+	// MinSamples.
+	Resources corev1.ResourceList `json:"resources,omitempty"`
+
+	Samples         int  `json:"samples"`
+	BelowMinSamples bool `json:"belowMinSamples,omitempty"`
+}
+
+// EstimateResourcesForAll returns the current estimate of every tracked group
+// with at least one sample, keyed by namespace/group. Estimates go through
+// the same smoothing and cache as EstimateResources, so both agree for a
+// group. Groups with fewer than MinSamples samples are flagged and carry no
+// resources.
+func (e *Estimator) EstimateResourcesForAll() map[string]GroupEstimate {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	estimates := make(map[string]GroupEstimate, len(e.histories))
+	for key, history := range e.histories {
+		history.mu.RLock()
+		samples := len(history.History)
+		history.mu.RUnlock()
+
+		if samples == 0 {
+			continue
+		}
+
+		estimate := GroupEstimate{
+			Namespace: history.Namespace,
+			Group:     history.GroupName,
+			Samples:   samples,
+		}
+		if samples < e.MinSamples {
+			estimate.BelowMinSamples = true
+		} else {
+			estimate.Resources, _, _ = e.estimateGroup(key, history)
+		}
+		estimates[key] = estimate
+	}
+
+	return estimates
+}
+
+// GroupCount returns the number of groups tracked.
+func (e *Estimator) GroupCount() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return len(e.histories)
 }
 
 // GetHistory returns the history for a specific group.
@@ -202,18 +1269,54 @@ func (e *Estimator) GetHistory(namespace, groupName string) (*GroupHistory, bool
 	return history, exists
 }
 
+// GlobalAverage returns the mean of the latest sample of every tracked group,
+// a fleet-wide view of typical group usage. Groups without samples are
+// skipped.
+func (e *Estimator) GlobalAverage() ResourceUsage {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var total ResourceUsage
+	count := 0
+	for _, history := range e.histories {
+		// Estimator lock before history lock, as everywhere else.
+		history.mu.RLock()
+		if n := len(history.History); n > 0 {
+			latest := history.History[n-1]
+			total.CPU += latest.CPU
+			total.Memory += latest.Memory
+			total.GPU += latest.GPU
+			count++
+		}
+		history.mu.RUnlock()
+	}
+
+	if count == 0 {
+		return ResourceUsage{}
+	}
+
+	return ResourceUsage{
+		CPU:    total.CPU / float64(count),
+		Memory: total.Memory / float64(count),
+		GPU:    total.GPU / float64(count),
+	}
+}
+
 // CleanOldHistory removes histories older than the specified duration.
 func (e *Estimator) CleanOldHistory(maxAge time.Duration) int {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	removed := 0
-	cutoff := time.Now().Add(-maxAge)
+	cutoff := e.Clock.Now().Add(-maxAge)
 
 	for key, history := range e.histories {
 		history.mu.Lock()
 		if len(history.History) > 0 && history.History[len(history.History)-1].Timestamp.Before(cutoff) {
 			delete(e.histories, key)
+			e.cache.invalidate(key)
+			e.forgetSmoothing(key)
+			e.forgetSamples(history)
 			removed++
 		}
 		history.mu.Unlock()
@@ -222,3 +1325,164 @@ func (e *Estimator) CleanOldHistory(maxAge time.Duration) int {
 	e.logger.Info("cleaned old histories", "removed", removed)
 	return removed
 }
+
+// MergeHistory moves the samples of the group at fromKey into the group at
+// toKey, both in namespace/groupName form, and removes the source. Samples are
+// merged in chronological order and the destination keeps only its newest
+// maxSize entries. The destination is created when it does not exist.
+func (e *Estimator) MergeHistory(fromKey, toKey string) error {
+	if fromKey == toKey {
+		return fmt.Errorf("cannot merge history %s into itself", fromKey)
+	}
+
+	namespace, groupName, ok := strings.Cut(toKey, "/")
+	if !ok {
+		return fmt.Errorf("invalid history key %q, expected namespace/groupName", toKey)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	from, exists := e.histories[fromKey]
+	if !exists {
+		return fmt.Errorf("%w for %s", ErrNoHistory, fromKey)
+	}
+
+	to, exists := e.histories[toKey]
+	if !exists {
+		to = e.newHistory(groupName, namespace)
+		e.histories[toKey] = to
+	}
+
+	from.mu.RLock()
+	to.mu.Lock()
+	merged := make([]ResourceUsage, 0, len(to.History)+len(from.History))
+	merged = append(merged, to.History...)
+	merged = append(merged, from.History...)
+	from.mu.RUnlock()
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+	if len(merged) > to.maxSize {
+		merged = merged[len(merged)-to.maxSize:]
+	}
+	to.History = merged
+	to.mu.Unlock()
+
+	delete(e.histories, fromKey)
+	e.cache.invalidate(fromKey)
+	e.cache.invalidate(toKey)
+	e.forgetSmoothing(fromKey)
+	e.forgetSamples(from)
+	e.reportSamples(to)
+
+	e.logger.Info("merged history", "from", fromKey, "to", toKey, "samples", len(merged))
+	return nil
+}
+
+// Downsample collapses every factor consecutive samples older than olderThan
+// into a single sample holding their average, stamped at the midpoint of the
+// collapsed run. Recent samples keep their full resolution. It returns the
+// number of samples removed.
+func (e *Estimator) Downsample(olderThan time.Duration, factor int) int {
+	if factor < 2 {
+		return 0
+	}
+
+	e.mu.RLock()
+	histories := make([]*GroupHistory, 0, len(e.histories))
+	for _, history := range e.histories {
+		histories = append(histories, history)
+	}
+	e.mu.RUnlock()
+
+	cutoff := e.Clock.Now().Add(-olderThan)
+	removed := 0
+	for _, history := range histories {
+		removed += history.downsample(cutoff, factor)
+		e.reportSamples(history)
+	}
+	if removed > 0 {
+		e.cache.invalidateAll()
+	}
+
+	e.logger.Info("downsampled histories", "removed", removed)
+	return removed
+}
+
+// TrimAll trims every group's history to its n most recent samples with
+// GroupHistory.TrimToCount and returns how many samples were dropped. Groups
+// recorded afterwards still get the estimator's configured history size.
+func (e *Estimator) TrimAll(n int) int {
+	e.mu.RLock()
+	histories := make([]*GroupHistory, 0, len(e.histories))
+	for _, history := range e.histories {
+		histories = append(histories, history)
+	}
+	e.mu.RUnlock()
+
+	removed := 0
+	for _, history := range histories {
+		removed += history.TrimToCount(n)
+		e.reportSamples(history)
+	}
+	if removed > 0 {
+		e.cache.invalidateAll()
+	}
+
+	e.logger.Info("trimmed histories", "keep", n, "removed", removed)
+	return removed
+}
+
+// downsample collapses samples taken before cutoff in runs of factor.
+func (gh *GroupHistory) downsample(cutoff time.Time, factor int) int {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	old := 0
+	for old < len(gh.History) && gh.History[old].Timestamp.Before(cutoff) {
+		old++
+	}
+
+	compacted := make([]ResourceUsage, 0, len(gh.History))
+	for start := 0; start < old; start += factor {
+		end := start + factor
+		if end > old {
+			end = old
+		}
+		compacted = append(compacted, collapse(gh.History[start:end]))
+	}
+	compacted = append(compacted, gh.History[old:]...)
+
+	removed := len(gh.History) - len(compacted)
+	gh.History = compacted
+	return removed
+}
+
+// coalesce merges next into latest, keeping the larger of each resource and
+// weight so a burst cannot hide a peak. The timestamp stays that of latest,
+// so a steady stream still appends once per interval.
+func coalesce(latest, next ResourceUsage) ResourceUsage {
+	latest.CPU = math.Max(latest.CPU, next.CPU)
+	latest.Memory = math.Max(latest.Memory, next.Memory)
+	latest.GPU = math.Max(latest.GPU, next.GPU)
+	latest.Weight = math.Max(latest.Weight, next.Weight)
+	latest.Replicas = next.Replicas
+	return latest
+}
+
+// collapse averages samples into one stamped at the midpoint of their span.
+func collapse(samples []ResourceUsage) ResourceUsage {
+	if len(samples) == 1 {
+		return samples[0]
+	}
+
+	first := samples[0].Timestamp
+	span := samples[len(samples)-1].Timestamp.Sub(first)
+
+	collapsed := mean(samples)
+	collapsed.Timestamp = first.Add(span / 2)
+	collapsed.Replicas = samples[len(samples)-1].Replicas
+	return collapsed
+}
@@ -1,20 +1,46 @@
 package estimator
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vjranagit/volcano/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
 func TestNewGroupHistory(t *testing.T) {
 	gh := NewGroupHistory("test-group", "default", 100)
 	assert.Equal(t, "test-group", gh.GroupName)
 	assert.Equal(t, "default", gh.Namespace)
-	assert.Equal(t, 0, len(gh.History))
+	assert.Equal(t, 0, gh.Len())
 	assert.Equal(t, 100, gh.maxSize)
 }
 
@@ -25,15 +51,78 @@ func TestGroupHistory_AddUsage(t *testing.T) {
 	gh.AddUsage(150, 3072, 1)
 	gh.AddUsage(200, 4096, 2)
 
-	assert.Equal(t, 3, len(gh.History))
+	assert.Equal(t, 3, gh.Len())
 	assert.Equal(t, 200.0, gh.History[2].CPU)
 
 	// Test FIFO eviction
 	gh.AddUsage(250, 5120, 2)
-	assert.Equal(t, 3, len(gh.History))
+	assert.Equal(t, 3, gh.Len())
 	assert.Equal(t, 150.0, gh.History[0].CPU) // First entry evicted
 }
 
+func TestGroupHistory_EvictionReusesBuffer(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 5)
+	for i := 1; i <= 23; i++ {
+		gh.AddUsage(float64(i), 1024, 0)
+	}
+
+	// Eviction across many wraps of the buffer keeps the newest samples in
+	// order.
+	var cpu []float64
+	gh.ForEach(func(usage ResourceUsage) bool {
+		cpu = append(cpu, usage.CPU)
+		return true
+	})
+	assert.Equal(t, []float64{19, 20, 21, 22, 23}, cpu)
+	assert.Equal(t, 21.0, gh.GetAverage().CPU)
+	assert.Equal(t, 23.0, gh.GetPeak().CPU)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		gh.AddUsage(1, 1024, 0)
+	})
+	assert.Zero(t, allocs)
+	assert.Equal(t, 5, gh.Len())
+}
+
+func TestGroupHistory_TrimToCount(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 20)
+	for i := 1; i <= 10; i++ {
+		gh.AddUsage(float64(i*100), 1024, 0)
+	}
+
+	assert.Equal(t, 7, gh.TrimToCount(3))
+	require.Len(t, gh.History, 3)
+	assert.Equal(t, 800.0, gh.History[0].CPU)
+	assert.Equal(t, 900.0, gh.History[1].CPU)
+	assert.Equal(t, 1000.0, gh.History[2].CPU)
+
+	// The lowered maximum holds for new samples.
+	gh.AddUsage(1100, 1024, 0)
+	require.Len(t, gh.History, 3)
+	assert.Equal(t, 900.0, gh.History[0].CPU)
+
+	assert.Equal(t, 0, gh.TrimToCount(5))
+	assert.Equal(t, 0, gh.TrimToCount(0))
+	assert.Equal(t, 3, gh.Len())
+}
+
+func TestEstimator_TrimAll(t *testing.T) {
+	est := NewEstimator(100, slog.Default())
+	for i := 0; i < 10; i++ {
+		est.RecordUsage("default", "a", 1000, 1024, 0)
+		est.RecordUsage("default", "b", 1000, 1024, 0)
+	}
+	est.RecordUsage("default", "c", 1000, 1024, 0)
+
+	assert.Equal(t, 14, est.TrimAll(3))
+
+	for group, want := range map[string]int{"a": 3, "b": 3, "c": 1} {
+		history, ok := est.GetHistory("default", group)
+		require.True(t, ok)
+		assert.Equal(t, want, history.Len(), group)
+	}
+}
+
 func TestGroupHistory_GetAverage(t *testing.T) {
 	gh := NewGroupHistory("test", "default", 10)
 
@@ -70,12 +159,40 @@ func TestGroupHistory_EmptyHistory(t *testing.T) {
 	assert.Equal(t, 0.0, peak.CPU)
 }
 
+func TestGroupHistory_GetRate(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 10)
+
+	gh.AddUsage(100, 2000, 1)
+	gh.AddUsage(300, 1000, 1)
+
+	start := time.Now()
+	gh.History[0].Timestamp = start
+	gh.History[1].Timestamp = start.Add(10 * time.Second)
+
+	rate := gh.GetRate()
+	assert.Equal(t, 20.0, rate.CPU)
+	assert.Equal(t, -100.0, rate.Memory)
+	assert.Equal(t, 0.0, rate.GPU)
+}
+
+func TestGroupHistory_GetRate_Degenerate(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 10)
+	assert.Equal(t, ResourceUsage{}, gh.GetRate())
+
+	gh.AddUsage(100, 2000, 1)
+	assert.Equal(t, ResourceUsage{}, gh.GetRate())
+
+	gh.AddUsage(200, 4000, 2)
+	gh.History[1].Timestamp = gh.History[0].Timestamp
+	assert.Equal(t, ResourceUsage{}, gh.GetRate())
+}
+
 func TestNewEstimator(t *testing.T) {
 	est := NewEstimator(100, slog.Default())
 	assert.NotNil(t, est)
 	assert.NotNil(t, est.logger)
 	assert.Equal(t, 100, est.maxSize)
-	assert.Equal(t, 0, len(est.histories))
+	assert.Equal(t, 0, est.GroupCount())
 }
 
 func TestEstimator_RecordUsage(t *testing.T) {
@@ -86,7 +203,39 @@ func TestEstimator_RecordUsage(t *testing.T) {
 
 	history, exists := est.GetHistory("default", "group1")
 	require.True(t, exists)
-	assert.Equal(t, 2, len(history.History))
+	assert.Equal(t, 2, history.Len())
+	assert.Equal(t, 1, est.GroupCount())
+
+	est.RecordUsage("default", "group2", 100, 2048, 1)
+	assert.Equal(t, 2, est.GroupCount())
+	assert.Equal(t, 2, history.Len())
+}
+
+func TestEstimator_OnRecord(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	var calls []ResourceUsage
+	est.OnRecord(func(namespace, groupName string, usage ResourceUsage) {
+		assert.Equal(t, "default", namespace)
+		assert.Equal(t, "group1", groupName)
+
+		// The sample is stored before callbacks run, and no lock is held.
+		history, exists := est.GetHistory(namespace, groupName)
+		require.True(t, exists)
+		history.GetAverage()
+		assert.Equal(t, len(calls)+1, history.Len())
+		calls = append(calls, usage)
+	})
+
+	est.RecordUsage("default", "group1", 100, 2048, 1)
+	est.RecordUsage("default", "group1", 150, 3072, 0)
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, 100.0, calls[0].CPU)
+	assert.Equal(t, 2048.0, calls[0].Memory)
+	assert.Equal(t, 1.0, calls[0].GPU)
+	assert.Equal(t, 150.0, calls[1].CPU)
+	assert.False(t, calls[1].Timestamp.IsZero())
 }
 
 func TestEstimator_EstimateResources(t *testing.T) {
@@ -115,12 +264,74 @@ func TestEstimator_EstimateResources(t *testing.T) {
 	assert.True(t, gpu.Value() > 0)
 }
 
+func TestEstimator_EstimateResources_GPUResourceName(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.GPUResourceName = "amd.com/gpu"
+
+	est.RecordUsage("default", "ml-training", 1000, 4096, 2)
+
+	resources, err := est.EstimateResources("default", "ml-training")
+	require.NoError(t, err)
+
+	gpu, exists := resources["amd.com/gpu"]
+	assert.True(t, exists)
+	assert.Equal(t, int64(2), gpu.Value())
+	assert.NotContains(t, resources, DefaultGPUResourceName)
+}
+
+func TestEstimator_EstimateResources_FractionalGPU(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsage("default", "inference", 500, 1024, 0.25)
+
+	// Whole GPUs by default truncate a quarter device to zero.
+	resources, err := est.EstimateResources("default", "inference")
+	require.NoError(t, err)
+	gpu := resources[DefaultGPUResourceName]
+	assert.True(t, gpu.IsZero())
+
+	est.FractionalGPU = true
+	resources, err = est.EstimateResources("default", "inference")
+	require.NoError(t, err)
+	gpu = resources[DefaultGPUResourceName]
+	assert.Equal(t, int64(250), gpu.MilliValue())
+	assert.Equal(t, "250m", gpu.String())
+}
+
+func TestEstimator_EstimateResources_FractionalGPURounds(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.FractionalGPU = true
+
+	// The blends come out a hair under 0.1 and 0.395 in floating point.
+	for _, tt := range []struct {
+		group string
+		gpus  []float64
+		want  string
+	}{
+		{group: "steady", gpus: []float64{0.1, 0.1}, want: "100m"},
+		{group: "bursty", gpus: []float64{0.2, 0.5}, want: "395m"},
+	} {
+		for _, gpu := range tt.gpus {
+			est.RecordUsage("default", tt.group, 1, 1024, gpu)
+		}
+		resources, err := est.EstimateResources("default", tt.group)
+		require.NoError(t, err)
+		gpu := resources[DefaultGPUResourceName]
+		assert.Equal(t, tt.want, gpu.String(), tt.group)
+	}
+}
+
 func TestEstimator_EstimateResources_NoHistory(t *testing.T) {
 	est := NewEstimator(10, slog.Default())
 
 	_, err := est.EstimateResources("default", "unknown")
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "no history found")
+	assert.ErrorIs(t, err, ErrNoHistory)
+	assert.EqualError(t, err, "no history found for default/unknown")
+
+	_, _, err = est.EstimateRequestsAndLimits("default", "unknown")
+	assert.ErrorIs(t, err, ErrNoHistory)
+	_, err = est.EstimateResourcesAt("default", "unknown", time.Now())
+	assert.ErrorIs(t, err, ErrNoHistory)
 }
 
 func TestEstimator_EstimateResources_WeightedAverage(t *testing.T) {
@@ -144,23 +355,55 @@ func TestEstimator_EstimateResources_WeightedAverage(t *testing.T) {
 }
 
 func TestEstimator_CleanOldHistory(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
 	est := NewEstimator(10, slog.Default())
+	est.Clock = clock
 
-	// Add recent usage
 	est.RecordUsage("default", "group1", 100, 2048, 1)
 
-	// Add old usage by manipulating timestamp
-	history, _ := est.GetHistory("default", "group1")
-	history.mu.Lock()
-	history.History[0].Timestamp = time.Now().Add(-48 * time.Hour)
-	history.mu.Unlock()
+	clock.Advance(23 * time.Hour)
+	est.RecordUsage("default", "group2", 100, 2048, 1)
 
 	// Clean histories older than 24 hours
+	clock.Advance(2 * time.Hour)
 	removed := est.CleanOldHistory(24 * time.Hour)
 	assert.Equal(t, 1, removed)
 
 	_, exists := est.GetHistory("default", "group1")
 	assert.False(t, exists)
+	_, exists = est.GetHistory("default", "group2")
+	assert.True(t, exists)
+}
+
+func TestEstimator_MinIntervalCoalesces(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	est := NewEstimator(100, slog.Default())
+	est.Clock = clock
+	est.MinInterval = 10 * time.Second
+
+	// 60 samples a second apart land in six 10-second buckets.
+	for i := 0; i < 60; i++ {
+		est.RecordUsage("default", "tight-loop", float64(i%7), 1024, 0)
+		clock.Advance(time.Second)
+	}
+
+	history, ok := est.GetHistory("default", "tight-loop")
+	require.True(t, ok)
+	assert.Equal(t, 6, history.Len())
+
+	// Each retained sample keeps the peak of the burst it absorbed.
+	history.ForEach(func(usage ResourceUsage) bool {
+		assert.Equal(t, 6.0, usage.CPU)
+		return true
+	})
+
+	// Without MinInterval every sample is kept.
+	est.MinInterval = 0
+	for i := 0; i < 5; i++ {
+		est.RecordUsage("default", "unguarded", 1, 1024, 0)
+	}
+	history, _ = est.GetHistory("default", "unguarded")
+	assert.Equal(t, 5, history.Len())
 }
 
 func TestEstimator_ConcurrentAccess(t *testing.T) {
@@ -185,5 +428,828 @@ func TestEstimator_ConcurrentAccess(t *testing.T) {
 
 	history, exists := est.GetHistory("default", "concurrent-test")
 	require.True(t, exists)
-	assert.True(t, len(history.History) > 0)
+	assert.True(t, history.Len() > 0)
+}
+
+func TestEstimator_Downsample(t *testing.T) {
+	est := NewEstimator(20, slog.Default())
+
+	for i := 0; i < 6; i++ {
+		est.RecordUsage("default", "group1", float64(100*(i+1)), 1000, 0)
+	}
+
+	// Samples 0-4 are a day old, one hour apart; sample 5 is recent.
+	history, _ := est.GetHistory("default", "group1")
+	start := time.Now().Add(-24 * time.Hour)
+	history.mu.Lock()
+	for i := 0; i < 5; i++ {
+		history.History[i].Timestamp = start.Add(time.Duration(i) * time.Hour)
+	}
+	history.mu.Unlock()
+
+	removed := est.Downsample(time.Hour, 2)
+	assert.Equal(t, 2, removed)
+
+	require.Equal(t, 4, history.Len())
+	assert.Equal(t, 150.0, history.History[0].CPU)
+	assert.Equal(t, start.Add(30*time.Minute), history.History[0].Timestamp)
+	assert.Equal(t, 350.0, history.History[1].CPU)
+	assert.Equal(t, start.Add(150*time.Minute), history.History[1].Timestamp)
+	assert.Equal(t, 500.0, history.History[2].CPU) // trailing partial run
+	assert.Equal(t, 600.0, history.History[3].CPU) // recent sample untouched
+}
+
+func TestEstimator_MergeHistory(t *testing.T) {
+	est := NewEstimator(4, slog.Default())
+
+	est.RecordUsage("default", "old-name", 100, 1000, 0)
+	est.RecordUsage("default", "old-name", 200, 1000, 0)
+	est.RecordUsage("default", "new-name", 300, 1000, 0)
+	est.RecordUsage("default", "new-name", 400, 1000, 0)
+	est.RecordUsage("default", "new-name", 500, 1000, 0)
+
+	// Interleave the two groups in time.
+	start := time.Now().Add(-time.Hour)
+	from, _ := est.GetHistory("default", "old-name")
+	from.History[0].Timestamp = start
+	from.History[1].Timestamp = start.Add(2 * time.Minute)
+	to, _ := est.GetHistory("default", "new-name")
+	to.History[0].Timestamp = start.Add(time.Minute)
+	to.History[1].Timestamp = start.Add(3 * time.Minute)
+	to.History[2].Timestamp = start.Add(4 * time.Minute)
+
+	require.NoError(t, est.MergeHistory("default/old-name", "default/new-name"))
+
+	_, exists := est.GetHistory("default", "old-name")
+	assert.False(t, exists)
+
+	merged, exists := est.GetHistory("default", "new-name")
+	require.True(t, exists)
+	require.Equal(t, 4, len(merged.History)) // oldest sample evicted by maxSize
+	cpus := make([]float64, 0, len(merged.History))
+	for _, usage := range merged.History {
+		cpus = append(cpus, usage.CPU)
+	}
+	assert.Equal(t, []float64{300, 200, 400, 500}, cpus)
+}
+
+func TestEstimator_MergeHistory_CreatesDestination(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsage("default", "old-name", 100, 1000, 0)
+
+	require.NoError(t, est.MergeHistory("default/old-name", "team-a/new-name"))
+
+	history, exists := est.GetHistory("team-a", "new-name")
+	require.True(t, exists)
+	assert.Equal(t, "new-name", history.GroupName)
+	assert.Equal(t, "team-a", history.Namespace)
+	assert.Equal(t, 1, history.Len())
+
+	err := est.MergeHistory("default/missing", "team-a/new-name")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNoHistory)
+}
+
+func TestGroupHistory_GetStdDev(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 10)
+
+	gh.AddUsage(2, 1000, 0)
+	gh.AddUsage(4, 3000, 0)
+	gh.AddUsage(4, 3000, 0)
+	gh.AddUsage(6, 1000, 0)
+
+	stddev := gh.GetStdDev()
+	assert.InDelta(t, 1.4142, stddev.CPU, 0.0001)
+	assert.Equal(t, 1000.0, stddev.Memory)
+	assert.Equal(t, 0.0, stddev.GPU)
+}
+
+func TestEstimator_EstimateResourcesWithConfidence(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	// CPU mean 2, stddev 1; memory mean 2000, stddev 1000; GPU mean 2, stddev 2.
+	est.RecordUsage("default", "test", 1, 1000, 0)
+	est.RecordUsage("default", "test", 3, 3000, 4)
+
+	result, err := est.EstimateResourcesWithConfidence("default", "test", 1.0)
+	require.NoError(t, err)
+
+	expected, err := est.EstimateResources("default", "test")
+	require.NoError(t, err)
+	assert.Equal(t, expected, result.Estimate)
+
+	lowerCPU := result.Lower[corev1.ResourceCPU]
+	upperCPU := result.Upper[corev1.ResourceCPU]
+	assert.Equal(t, int64(1000), lowerCPU.MilliValue())
+	assert.Equal(t, int64(3000), upperCPU.MilliValue())
+
+	lowerMem := result.Lower[corev1.ResourceMemory]
+	upperMem := result.Upper[corev1.ResourceMemory]
+	assert.Equal(t, int64(1000), lowerMem.Value())
+	assert.Equal(t, int64(3000), upperMem.Value())
+
+	// The GPU lower bound is clamped at zero and therefore omitted.
+	assert.NotContains(t, result.Lower, DefaultGPUResourceName)
+	upperGPU := result.Upper[DefaultGPUResourceName]
+	assert.Equal(t, int64(4), upperGPU.Value())
+
+	_, err = est.EstimateResourcesWithConfidence("default", "unknown", 1.0)
+	assert.ErrorIs(t, err, ErrNoHistory)
+
+	// One sample has no spread to bound.
+	est.RecordUsage("default", "single", 1, 1000, 0)
+	_, err = est.EstimateResourcesWithConfidence("default", "single", 1.0)
+	assert.ErrorIs(t, err, ErrInsufficientData)
+}
+
+func TestGroupHistory_GetAverageForHour(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 10)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gh.AddUsage(1, 1000, 0)
+	gh.AddUsage(3, 3000, 0)
+	gh.AddUsage(10, 8000, 0)
+	gh.History[0].Timestamp = day.Add(2 * time.Hour)
+	gh.History[1].Timestamp = day.Add(2*time.Hour + 30*time.Minute)
+	gh.History[2].Timestamp = day.Add(14 * time.Hour)
+
+	night := gh.GetAverageForHour(2)
+	assert.Equal(t, 2.0, night.CPU)
+	assert.Equal(t, 2000.0, night.Memory)
+
+	afternoon := gh.GetAverageForHour(14)
+	assert.Equal(t, 10.0, afternoon.CPU)
+	assert.Equal(t, 8000.0, afternoon.Memory)
+
+	// No samples at 8am: fall back to the overall average.
+	assert.Equal(t, gh.GetAverage(), gh.GetAverageForHour(8))
+}
+
+func TestEstimator_EstimateResourcesAt(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	est.RecordUsage("default", "nightly", 1, 1000, 0)
+	est.RecordUsage("default", "nightly", 11, 1000, 0)
+
+	history, _ := est.GetHistory("default", "nightly")
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history.History[0].Timestamp = day.Add(12 * time.Hour)
+	history.History[1].Timestamp = day.Add(2 * time.Hour)
+
+	// 70% of the 2am average (11) + 30% of the peak (11).
+	resources, err := est.EstimateResourcesAt("default", "nightly", day.Add(2*time.Hour))
+	require.NoError(t, err)
+	cpu := resources[corev1.ResourceCPU]
+	assert.Equal(t, int64(11000), cpu.MilliValue())
+
+	// 70% of the noon average (1) + 30% of the peak (11).
+	resources, err = est.EstimateResourcesAt("default", "nightly", day.Add(12*time.Hour))
+	require.NoError(t, err)
+	cpu = resources[corev1.ResourceCPU]
+	assert.Equal(t, int64(4000), cpu.MilliValue())
+
+	_, err = est.EstimateResourcesAt("default", "unknown", day)
+	require.Error(t, err)
+}
+
+func TestEstimator_EstimateResourcesAtMixedLocations(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	est.RecordUsage("default", "nightly", 1, 1000, 0)
+	est.RecordUsage("default", "nightly", 11, 1000, 0)
+
+	// Samples carry a UTC+5 location; 07:00 there is 02:00 UTC.
+	history, _ := est.GetHistory("default", "nightly")
+	local := time.FixedZone("UTC+5", 5*60*60)
+	history.History[0].Timestamp = time.Date(2024, 1, 1, 17, 0, 0, 0, local)
+	history.History[1].Timestamp = time.Date(2024, 1, 1, 7, 0, 0, 0, local)
+
+	// The same instant asked for in UTC lands in the 2am bucket.
+	resources, err := est.EstimateResourcesAt("default", "nightly", time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	cpu := resources[corev1.ResourceCPU]
+	assert.Equal(t, int64(11000), cpu.MilliValue())
+}
+
+func TestEstimator_RecordUsageWithDuration(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	// A 10 second spike against an hour of steady use.
+	est.RecordUsageWithDuration("default", "group1", 100, 10000, 0, 10)
+	est.RecordUsageWithDuration("default", "group1", 10, 1000, 0, 3600)
+
+	history, exists := est.GetHistory("default", "group1")
+	require.True(t, exists)
+
+	avg := history.GetAverage()
+	assert.InDelta(t, (100*10+10*3600)/3610.0, avg.CPU, 0.0001)
+	assert.InDelta(t, (10000*10+1000*3600)/3610.0, avg.Memory, 0.0001)
+	assert.Less(t, avg.CPU, 11.0)
+
+	// Plain samples weigh one second each.
+	est.RecordUsage("default", "group2", 100, 0, 0)
+	est.RecordUsage("default", "group2", 10, 0, 0)
+	history, _ = est.GetHistory("default", "group2")
+	assert.Equal(t, 55.0, history.GetAverage().CPU)
+}
+
+func TestEstimator_EstimateRequestsAndLimits(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	est.RecordUsage("default", "varied", 1, 1024, 0)
+	est.RecordUsage("default", "varied", 4, 8192, 2)
+	est.RecordUsage("default", "varied", 2, 2048, 1)
+
+	requests, limits, err := est.EstimateRequestsAndLimits("default", "varied")
+	require.NoError(t, err)
+
+	for name, request := range requests {
+		limit, ok := limits[name]
+		require.True(t, ok, "limit missing for %s", name)
+		assert.GreaterOrEqual(t, limit.Cmp(request), 0, "limit below request for %s", name)
+	}
+
+	limitCPU := limits[corev1.ResourceCPU]
+	assert.Equal(t, int64(4000), limitCPU.MilliValue())
+
+	est.LimitFactor = 1.5
+	_, limits, err = est.EstimateRequestsAndLimits("default", "varied")
+	require.NoError(t, err)
+	limitMem := limits[corev1.ResourceMemory]
+	assert.Equal(t, int64(12288), limitMem.Value())
+
+	_, _, err = est.EstimateRequestsAndLimits("default", "unknown")
+	require.Error(t, err)
+}
+
+func TestEstimator_GlobalAverage(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	assert.Equal(t, ResourceUsage{}, est.GlobalAverage())
+
+	est.RecordUsage("default", "a", 100, 1000, 0)
+	est.RecordUsage("default", "a", 1, 1000, 0) // latest for a
+	est.RecordUsage("default", "b", 2, 2000, 1)
+	est.RecordUsage("team-x", "c", 6, 6000, 2)
+
+	// A tracked group that lost all its samples is skipped.
+	empty := NewGroupHistory("empty", "default", 10)
+	est.mu.Lock()
+	est.histories["default/empty"] = empty
+	est.mu.Unlock()
+
+	avg := est.GlobalAverage()
+	assert.Equal(t, 3.0, avg.CPU)
+	assert.Equal(t, 3000.0, avg.Memory)
+	assert.Equal(t, 1.0, avg.GPU)
+}
+
+func TestEstimator_GlobalAverage_Concurrent(t *testing.T) {
+	est := NewEstimator(100, slog.Default())
+
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func(id int) {
+			for j := 0; j < 50; j++ {
+				est.RecordUsage("default", fmt.Sprintf("group-%d", id), float64(j), 1000, 0)
+				est.GlobalAverage()
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	assert.Equal(t, 49.0, est.GlobalAverage().CPU)
+}
+
+func TestEstimator_ContextCancelled(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	require.NoError(t, est.RecordUsageContext(context.Background(), "default", "test-group", 1.0, 1024, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := est.RecordUsageContext(ctx, "default", "test-group", 2.0, 2048, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	history, _ := est.GetHistory("default", "test-group")
+	assert.Equal(t, 1, history.Len())
+
+	err = est.RecordUsageContext(ctx, "default", "other-group", 2.0, 2048, 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, exists := est.GetHistory("default", "other-group")
+	assert.False(t, exists)
+
+	resources, err := est.EstimateResourcesContext(ctx, "default", "test-group")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, resources)
+
+	resources, err = est.EstimateResourcesContext(context.Background(), "default", "test-group")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), resources.Cpu().MilliValue())
+}
+
+func TestGroupHistory_WindowedAccessors(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	gh := NewGroupHistory("test", "default", 10)
+	gh.Clock = clock
+
+	gh.AddUsage(16.0, 8192, 4) // one-off spike
+	clock.Advance(48 * time.Hour)
+	gh.AddUsage(2.0, 1024, 1)
+	gh.AddUsage(4.0, 2048, 1)
+
+	assert.Equal(t, 16.0, gh.GetPeak().CPU)
+
+	peak := gh.GetPeakSince(24 * time.Hour)
+	assert.Equal(t, 4.0, peak.CPU)
+	assert.Equal(t, 2048.0, peak.Memory)
+	assert.Equal(t, 1.0, peak.GPU)
+
+	avg := gh.GetAverageSince(24 * time.Hour)
+	assert.Equal(t, 3.0, avg.CPU)
+	assert.Equal(t, 1536.0, avg.Memory)
+
+	// An empty window falls back to the most recent sample.
+	clock.Advance(72 * time.Hour)
+	assert.Equal(t, 4.0, gh.GetPeakSince(time.Hour).CPU)
+	assert.Equal(t, 4.0, gh.GetAverageSince(time.Hour).CPU)
+
+	empty := NewGroupHistory("empty", "default", 10)
+	assert.Equal(t, ResourceUsage{}, empty.GetPeakSince(time.Hour))
+	assert.Equal(t, ResourceUsage{}, empty.GetAverageSince(time.Hour))
+}
+
+func TestEstimator_EstimateResourcesWithFloor(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsage("default", "ramping", 0.5, 512*1024*1024, 0)
+
+	floor := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("2"),
+		corev1.ResourceMemory:           resource.MustParse("256Mi"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+	}
+	resources, err := est.EstimateResourcesWithFloor("default", "ramping", floor)
+	require.NoError(t, err)
+
+	// CPU is below the floor, memory above it, and storage has no history.
+	assert.Equal(t, "2", resources.Cpu().String())
+	assert.Equal(t, "512Mi", resources.Memory().String())
+	assert.Equal(t, "1Gi", resources.StorageEphemeral().String())
+
+	// The unfloored estimate is unchanged.
+	resources, err = est.EstimateResources("default", "ramping")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), resources.Cpu().MilliValue())
+
+	_, err = est.EstimateResourcesWithFloor("default", "missing", floor)
+	assert.ErrorIs(t, err, ErrNoHistory)
+}
+
+func TestEstimator_OutlierRejection(t *testing.T) {
+	record := func(est *Estimator) {
+		for i := 0; i < 9; i++ {
+			est.RecordUsage("default", "steady", 2.0+0.1*float64(i%3), 1024*1024*1024, 1)
+		}
+		est.RecordUsage("default", "steady", 2.1, 1000*1024*1024*1024, 1) // metrics glitch
+	}
+
+	est := NewEstimator(20, slog.Default())
+	record(est)
+	history, _ := est.GetHistory("default", "steady")
+	assert.Equal(t, 1000.0*1024*1024*1024, history.GetPeak().Memory)
+	assert.Greater(t, history.GetAverage().Memory, 100.0*1024*1024*1024)
+
+	est = NewEstimator(20, slog.Default())
+	est.OutlierMADs = 3
+	record(est)
+	history, _ = est.GetHistory("default", "steady")
+	assert.Equal(t, 1024.0*1024*1024, history.GetPeak().Memory)
+	assert.Equal(t, 1024.0*1024*1024, history.GetAverage().Memory)
+	assert.Equal(t, 2.2, history.GetPeak().CPU)
+	assert.InDelta(t, 2.1, history.GetAverage().CPU, 1e-9)
+
+	resources, err := est.EstimateResources("default", "steady")
+	require.NoError(t, err)
+	assert.Equal(t, "1Gi", resources.Memory().String())
+}
+
+func TestEstimator_OutlierRejectionDiscreteResource(t *testing.T) {
+	est := NewEstimator(20, slog.Default())
+	est.OutlierMADs = 3
+	for i := 0; i < 10; i++ {
+		gpu := 4.0
+		if i%5 < 2 {
+			gpu = 8
+		}
+		est.RecordUsage("default", "training", 4, 1024*1024*1024, gpu)
+	}
+
+	// Most samples sit at the median, so the median absolute deviation is
+	// zero, yet the recurring 8 GPU demand is real.
+	history, _ := est.GetHistory("default", "training")
+	assert.Equal(t, 8.0, history.GetPeak().GPU)
+	assert.Equal(t, 8.0, history.GetPercentile(95).GPU)
+	assert.InDelta(t, 5.6, history.GetAverage().GPU, 1e-9)
+}
+
+func TestEstimator_OutlierRejectionAllStatistics(t *testing.T) {
+	est := NewEstimator(20, slog.Default())
+	est.OutlierMADs = 3
+	for i := 0; i < 19; i++ {
+		est.RecordUsage("default", "steady", 2.0+0.1*float64(i%3), 1024*1024*1024, 0)
+	}
+	// A memory glitch; its CPU reading is genuine and still counts.
+	est.RecordUsage("default", "steady", 2.3, 1000*1024*1024*1024, 0)
+
+	history, _ := est.GetHistory("default", "steady")
+	assert.Equal(t, 1024.0*1024*1024, history.GetPercentile(95).Memory)
+	assert.InDelta(t, 1024.0*1024*1024, history.GetDecayedPeak(PeakHalfLife).Memory, 1)
+	assert.Zero(t, history.GetStdDev().Memory)
+	assert.Equal(t, 2.3, history.GetPeak().CPU)
+
+	rec, err := est.RecommendResources("default", "steady")
+	require.NoError(t, err)
+	assert.Equal(t, "1Gi", rec.Limits.Memory().String())
+
+	confidence, err := est.EstimateResourcesWithConfidence("default", "steady", 1.96)
+	require.NoError(t, err)
+	assert.Equal(t, "1Gi", confidence.Upper.Memory().String())
+}
+
+func TestGroupHistory_GetDecayedPeak(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	gh := NewGroupHistory("test", "default", 10)
+	gh.Clock = clock
+
+	gh.AddUsage(16.0, 8192, 4) // one-off spike
+	clock.Advance(72 * time.Hour)
+	gh.AddUsage(5.0, 1024, 0)
+	gh.AddUsage(6.0, 2048, 0)
+
+	// Three half-lives shrink the spike to an eighth, below the recent peak.
+	decayed := gh.GetDecayedPeak(24 * time.Hour)
+	assert.Equal(t, 6.0, decayed.CPU)
+	assert.Equal(t, 2048.0, decayed.Memory)
+	assert.Equal(t, 0.5, decayed.GPU)
+	assert.Less(t, decayed.CPU, gh.GetPeak().CPU)
+
+	// A long half-life barely discounts the spike.
+	assert.InDelta(t, 16.0, gh.GetDecayedPeak(10000*time.Hour).CPU, 0.1)
+
+	assert.Equal(t, gh.GetPeak(), gh.GetDecayedPeak(0))
+	assert.Equal(t, ResourceUsage{}, NewGroupHistory("empty", "default", 10).GetDecayedPeak(time.Hour))
+}
+
+// groupSamples returns the volcano_estimator_group_samples value for a group
+// and whether the series exists.
+func groupSamples(t *testing.T, collector *metrics.Collector, namespace, group string) (float64, bool) {
+	t.Helper()
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "volcano_estimator_group_samples" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["namespace"] == namespace && labels["group"] == group {
+				return metric.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestEstimator_GroupSamplesMetric(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default())
+	est := NewEstimator(10, slog.Default())
+	est.Collector = collector
+
+	est.RecordUsage("samples-test", "group", 1.0, 1024, 0)
+	est.RecordUsage("samples-test", "group", 2.0, 1024, 0)
+
+	value, ok := groupSamples(t, collector, "samples-test", "group")
+	require.True(t, ok)
+	assert.Equal(t, 2.0, value)
+
+	history, _ := est.GetHistory("samples-test", "group")
+	history.History[1].Timestamp = time.Now().Add(-48 * time.Hour)
+	assert.Equal(t, 1, est.CleanOldHistory(24*time.Hour))
+
+	_, ok = groupSamples(t, collector, "samples-test", "group")
+	assert.False(t, ok)
+}
+
+func TestEstimator_EstimateCache(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	est := NewEstimator(10, slog.Default())
+	est.Clock = clock
+	est.CacheTTL = time.Minute
+
+	est.RecordUsage("default", "cached", 1.0, 1024, 0)
+	first, err := est.EstimateResources("default", "cached")
+	require.NoError(t, err)
+
+	// Samples added behind the estimator's back are not seen within the TTL.
+	history, _ := est.GetHistory("default", "cached")
+	history.AddUsage(10.0, 1024, 0)
+	second, err := est.EstimateResources("default", "cached")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// Recording through the estimator discards the cached estimate.
+	est.RecordUsage("default", "cached", 10.0, 1024, 0)
+	third, err := est.EstimateResources("default", "cached")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Cpu().MilliValue(), third.Cpu().MilliValue())
+
+	// Expired estimates are recomputed.
+	history.AddUsage(20.0, 1024, 0)
+	clock.Advance(2 * time.Minute)
+	fourth, err := est.EstimateResources("default", "cached")
+	require.NoError(t, err)
+	assert.Greater(t, fourth.Cpu().MilliValue(), third.Cpu().MilliValue())
+}
+
+func TestEstimateCache_InvalidateAllStopsInFlightEstimates(t *testing.T) {
+	cache := newEstimateCache()
+	now := time.Now()
+	resources := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+
+	// An estimate computed before invalidateAll is not stored, even for a
+	// group that had nothing cached.
+	_, generation, ok := cache.get("default/group", now)
+	require.False(t, ok)
+	cache.invalidateAll()
+	cache.put("default/group", generation, resources, now.Add(time.Minute))
+	_, _, ok = cache.get("default/group", now)
+	assert.False(t, ok)
+
+	// One computed afterwards is.
+	_, generation, _ = cache.get("default/group", now)
+	cache.put("default/group", generation, resources, now.Add(time.Minute))
+	cached, _, ok := cache.get("default/group", now)
+	require.True(t, ok)
+	assert.Equal(t, "1", cached.Cpu().String())
+}
+
+func TestEstimator_EstimateCacheDisabledByDefault(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	est.RecordUsage("default", "uncached", 1.0, 1024, 0)
+	first, err := est.EstimateResources("default", "uncached")
+	require.NoError(t, err)
+
+	history, _ := est.GetHistory("default", "uncached")
+	history.AddUsage(10.0, 1024, 0)
+	second, err := est.EstimateResources("default", "uncached")
+	require.NoError(t, err)
+	assert.Greater(t, second.Cpu().MilliValue(), first.Cpu().MilliValue())
+}
+
+func TestGroupHistory_ForEach(t *testing.T) {
+	gh := NewGroupHistory("test", "default", 10)
+	for _, cpu := range []float64{1, 2, 3, 4} {
+		gh.AddUsage(cpu, 1024, 0)
+	}
+
+	var sum float64
+	gh.ForEach(func(usage ResourceUsage) bool {
+		sum += usage.CPU
+		return true
+	})
+	assert.Equal(t, gh.GetAverage().CPU*float64(gh.Len()), sum)
+
+	// Returning false stops the iteration.
+	visited := 0
+	gh.ForEach(func(usage ResourceUsage) bool {
+		visited++
+		return usage.CPU < 2
+	})
+	assert.Equal(t, 2, visited)
+}
+
+func TestEstimator_Smoothing(t *testing.T) {
+	// With a single-sample history the raw estimate follows the latest
+	// sample, so an oscillating series makes it jump on every call.
+	estimates := func(smoothing float64) []float64 {
+		est := NewEstimator(1, slog.Default())
+		est.Smoothing = smoothing
+
+		var cpus []float64
+		for i := 0; i < 20; i++ {
+			cpu := 1.0
+			if i%2 == 1 {
+				cpu = 9.0
+			}
+			est.RecordUsage("default", "noisy", cpu, 1024, 0)
+			resources, err := est.EstimateResources("default", "noisy")
+			require.NoError(t, err)
+			cpus = append(cpus, float64(resources.Cpu().MilliValue())/1000)
+		}
+		return cpus
+	}
+
+	variance := func(values []float64) float64 {
+		var sum, sq float64
+		for _, v := range values {
+			sum += v
+		}
+		avg := sum / float64(len(values))
+		for _, v := range values {
+			sq += (v - avg) * (v - avg)
+		}
+		return sq / float64(len(values))
+	}
+
+	raw := estimates(0)
+	smoothed := estimates(0.8)
+
+	assert.Equal(t, raw[0], smoothed[0], "first estimate is not smoothed")
+	assert.Equal(t, 16.0, variance(raw))
+	assert.Less(t, variance(smoothed), variance(raw)/4)
+}
+
+func TestEstimator_SmoothingOnlyMovesOnNewSamples(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.Smoothing = 0.5
+
+	est.RecordUsage("default", "noisy", 1, 1024, 0)
+	first, err := est.EstimateResources("default", "noisy")
+	require.NoError(t, err)
+
+	est.RecordUsage("default", "noisy", 9, 1024, 0)
+	second, err := est.EstimateResources("default", "noisy")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Cpu().MilliValue(), second.Cpu().MilliValue())
+
+	// Reading again without new samples does not blend further.
+	for range 3 {
+		again, err := est.EstimateResources("default", "noisy")
+		require.NoError(t, err)
+		assert.Equal(t, second.Cpu().MilliValue(), again.Cpu().MilliValue())
+	}
+}
+
+func TestEstimator_EstimateResourcesForAllMatchesEstimateResources(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.Smoothing = 0.5
+	est.CacheTTL = time.Minute
+
+	est.RecordUsage("default", "noisy", 1, 1024, 0)
+	_, err := est.EstimateResources("default", "noisy")
+	require.NoError(t, err)
+	est.RecordUsage("default", "noisy", 9, 1024, 0)
+
+	// Both paths share the smoothed, cached estimate, whichever runs first.
+	all := est.EstimateResourcesForAll()
+	single, err := est.EstimateResources("default", "noisy")
+	require.NoError(t, err)
+	assert.Equal(t, single, all["default/noisy"].Resources)
+	assert.Equal(t, single, est.EstimateResourcesForAll()["default/noisy"].Resources)
+}
+
+func TestRecordUsageBatch_MatchesIndividualRecords(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	samples := []Sample{
+		{Namespace: "default", GroupName: "a", CPU: 1000, Memory: 1024},
+		{Namespace: "ml", GroupName: "b", CPU: 500, Memory: 512, GPU: 1, Seconds: 30},
+		{Namespace: "default", GroupName: "a", CPU: 1500, Memory: 2048},
+		{Namespace: "default", GroupName: "a", CPU: 1200, Memory: 1536, Seconds: 10},
+		{Namespace: "ml", GroupName: "b", CPU: 700, Memory: 768, GPU: 2},
+	}
+
+	individual := NewEstimator(3, slog.Default())
+	individual.Clock = clock
+	for _, s := range samples {
+		if s.Seconds > 0 {
+			individual.RecordUsageWithDuration(s.Namespace, s.GroupName, s.CPU, s.Memory, s.GPU, s.Seconds)
+		} else {
+			individual.RecordUsage(s.Namespace, s.GroupName, s.CPU, s.Memory, s.GPU)
+		}
+	}
+
+	batch := NewEstimator(3, slog.Default())
+	batch.Clock = clock
+	var recorded []string
+	batch.OnRecord(func(namespace, groupName string, usage ResourceUsage) {
+		recorded = append(recorded, namespace+"/"+groupName)
+	})
+	batch.RecordUsageBatch(samples)
+
+	assert.Equal(t, []string{"default/a", "default/a", "default/a", "ml/b", "ml/b"}, recorded)
+	for _, key := range [][2]string{{"default", "a"}, {"ml", "b"}} {
+		want, ok := individual.GetHistory(key[0], key[1])
+		require.True(t, ok)
+		got, ok := batch.GetHistory(key[0], key[1])
+		require.True(t, ok)
+		assert.Equal(t, want.History, got.History)
+
+		wantEstimate, err := individual.EstimateResources(key[0], key[1])
+		require.NoError(t, err)
+		gotEstimate, err := batch.EstimateResources(key[0], key[1])
+		require.NoError(t, err)
+		assert.Equal(t, wantEstimate, gotEstimate)
+	}
+}
+
+// benchmarkSamples returns n samples spread over groups groups.
+func benchmarkSamples(n, groups int) []Sample {
+	samples := make([]Sample, n)
+	for i := range samples {
+		samples[i] = Sample{
+			Namespace: "default",
+			GroupName: fmt.Sprintf("group-%d", i%groups),
+			CPU:       float64(i),
+			Memory:    1024,
+		}
+	}
+	return samples
+}
+
+func BenchmarkRecordUsage(b *testing.B) {
+	est := NewEstimator(100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	samples := benchmarkSamples(1000, 10)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		for _, s := range samples {
+			est.RecordUsage(s.Namespace, s.GroupName, s.CPU, s.Memory, s.GPU)
+		}
+	}
+}
+
+func BenchmarkGroupHistory_AddUsage(b *testing.B) {
+	gh := NewGroupHistory("bench", "default", 100)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		gh.AddUsage(1, 1024, 0)
+	}
+}
+
+func BenchmarkRecordUsageBatch(b *testing.B) {
+	est := NewEstimator(100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	samples := benchmarkSamples(1000, 10)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		est.RecordUsageBatch(samples)
+	}
+}
+
+func TestEstimator_RecordUsageUnits(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsageMillicores("default", "millicores", 1500, 1024*1024*1024, 0)
+	est.RecordUsageCores("default", "cores", 1.5, 1024*1024*1024, 0)
+
+	for _, group := range []string{"millicores", "cores"} {
+		resources, err := est.EstimateResources("default", group)
+		require.NoError(t, err)
+		assert.Equal(t, "1500m", resources.Cpu().String(), group)
+		assert.Equal(t, "1Gi", resources.Memory().String(), group)
+	}
+}
+
+func TestEstimator_EstimateResourcesRoundsCPU(t *testing.T) {
+	// 70% of 0.1 plus 30% of 0.1 is 0.09999… cores in floating point, which
+	// truncation would report as 99m.
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsage("default", "small", 0.1, 1024, 0)
+	est.RecordUsage("default", "small", 0.1, 1024, 0)
+
+	resources, err := est.EstimateResources("default", "small")
+	require.NoError(t, err)
+	assert.Equal(t, "100m", resources.Cpu().String())
+}
+
+func TestEstimator_EstimatePerReplica(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.RecordUsageWithReplicas("default", "workers", 8, 8*1024*1024*1024, 4, 4)
+	est.RecordUsageWithReplicas("default", "workers", 8, 8*1024*1024*1024, 4, 4)
+
+	perReplica := est.EstimatePerReplica("default", "workers", 1)
+	require.NotNil(t, perReplica)
+	assert.Equal(t, "2", perReplica.Cpu().String())
+	assert.Equal(t, "2Gi", perReplica.Memory().String())
+	gpu := perReplica[DefaultGPUResourceName]
+	assert.Equal(t, int64(1), gpu.Value())
+
+	scaled := est.EstimatePerReplica("default", "workers", 6)
+	assert.Equal(t, "12", scaled.Cpu().String())
+	assert.Equal(t, "12Gi", scaled.Memory().String())
+	gpu = scaled[DefaultGPUResourceName]
+	assert.Equal(t, int64(6), gpu.Value())
+
+	// Without replica counts there is nothing to scale by.
+	est.RecordUsage("default", "plain", 8, 1024, 0)
+	assert.Nil(t, est.EstimatePerReplica("default", "plain", 2))
+	assert.Nil(t, est.EstimatePerReplica("default", "unknown", 2))
+	assert.Nil(t, est.EstimatePerReplica("default", "workers", 0))
 }
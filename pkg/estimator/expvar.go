@@ -0,0 +1,34 @@
+package estimator
+
+import "expvar"
+
+// ExpvarName is the expvar map PublishExpvar publishes under.
+const ExpvarName = "volcano_estimator"
+
+// PublishExpvar publishes the number of tracked groups and the total samples
+// held as the "groups" and "samples" entries of the expvar map ExpvarName,
+// served at /debug/vars by the expvar handler. Values are computed when read,
+// so they always reflect recorded and cleaned history.
+//
+// Publishing is opt-in because expvar names are process-global: publishing
+// from another Estimator later makes the map report that one instead.
+func (e *Estimator) PublishExpvar() {
+	vars, ok := expvar.Get(ExpvarName).(*expvar.Map)
+	if !ok {
+		vars = expvar.NewMap(ExpvarName)
+	}
+
+	vars.Set("groups", expvar.Func(func() interface{} {
+		return e.GroupCount()
+	}))
+	vars.Set("samples", expvar.Func(func() interface{} {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+
+		samples := 0
+		for _, history := range e.histories {
+			samples += history.Len()
+		}
+		return samples
+	}))
+}
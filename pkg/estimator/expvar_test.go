@@ -0,0 +1,35 @@
+package estimator
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	est := NewEstimator(100, slog.Default())
+	est.PublishExpvar()
+
+	est.RecordUsage("default", "a", 1000, 1024, 0)
+	est.RecordUsage("default", "a", 1200, 1024, 0)
+	est.RecordUsage("default", "b", 500, 512, 0)
+
+	read := func() map[string]int {
+		vars, ok := expvar.Get(ExpvarName).(*expvar.Map)
+		require.True(t, ok)
+
+		var counts map[string]int
+		require.NoError(t, json.Unmarshal([]byte(vars.String()), &counts))
+		return counts
+	}
+	assert.Equal(t, map[string]int{"groups": 2, "samples": 3}, read())
+
+	// Publishing another estimator reuses the map instead of panicking.
+	other := NewEstimator(100, slog.Default())
+	other.PublishExpvar()
+	assert.Equal(t, map[string]int{"groups": 0, "samples": 0}, read())
+}
@@ -0,0 +1,77 @@
+package estimator
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ndjsonContentType is the JSON Lines media type the handler streams when a
+// client asks for it.
+const ndjsonContentType = "application/x-ndjson"
+
+// Handler returns a read-only HTTP handler that serves the result of
+// EstimateResourcesForAll as JSON, for tooling that wants current estimates
+// without scraping Prometheus. Mount it at /estimates.
+//
+// Clients that send Accept: application/x-ndjson instead get one
+// GroupEstimate per line, ordered by namespace/group and flushed as each is
+// written, so they can process large fleets incrementally.
+func (e *Estimator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if acceptsNDJSON(r) {
+			e.writeNDJSON(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(e.EstimateResourcesForAll()); err != nil {
+			e.logger.Error("failed to encode estimates", "error", err)
+		}
+	})
+}
+
+// writeNDJSON writes each estimate on its own line, flushing after each.
+func (e *Estimator) writeNDJSON(w http.ResponseWriter) {
+	estimates := e.EstimateResourcesForAll()
+	keys := make([]string, 0, len(estimates))
+	for key := range estimates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for _, key := range keys {
+		if err := encoder.Encode(estimates[key]); err != nil {
+			e.logger.Error("failed to encode estimate", "group", key, "error", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// acceptsNDJSON reports whether the Accept header lists the JSON Lines media
+// type.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == ndjsonContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package estimator
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_ServesEstimates(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.MinSamples = 2
+
+	est.RecordUsage("default", "training", 2.0, 1024*1024*1024, 1)
+	est.RecordUsage("default", "training", 2.0, 1024*1024*1024, 1)
+	est.RecordUsage("team-x", "new", 1.0, 1024, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/estimates", nil)
+	rec := httptest.NewRecorder()
+	est.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body, 2)
+
+	training := body["default/training"]
+	require.NotNil(t, training)
+	assert.Equal(t, "default", training["namespace"])
+	assert.Equal(t, "training", training["group"])
+	assert.Equal(t, 2.0, training["samples"])
+	assert.NotContains(t, training, "belowMinSamples")
+	resources, ok := training["resources"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "2", resources["cpu"])
+	assert.Equal(t, "1Gi", resources["memory"])
+	assert.Equal(t, "1", resources["nvidia.com/gpu"])
+
+	fresh := body["team-x/new"]
+	require.NotNil(t, fresh)
+	assert.Equal(t, 1.0, fresh["samples"])
+	assert.Equal(t, true, fresh["belowMinSamples"])
+	assert.NotContains(t, fresh, "resources")
+}
+
+func TestHandler_NDJSON(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.MinSamples = 1
+
+	est.RecordUsage("team-b", "eval", 1.0, 1024, 0)
+	est.RecordUsage("team-a", "training", 2.0, 1024*1024*1024, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/estimates", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	est.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.True(t, rec.Flushed)
+
+	var lines []GroupEstimate
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var estimate GroupEstimate
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &estimate))
+		lines = append(lines, estimate)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, "team-a", lines[0].Namespace)
+	assert.Equal(t, "training", lines[0].Group)
+	assert.Equal(t, "2", lines[0].Resources.Cpu().String())
+	assert.Equal(t, "team-b", lines[1].Namespace)
+	assert.Equal(t, "eval", lines[1].Group)
+}
+
+func TestHandler_ReadOnly(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	req := httptest.NewRequest(http.MethodPost, "/estimates", nil)
+	rec := httptest.NewRecorder()
+	est.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
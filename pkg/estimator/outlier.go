@@ -0,0 +1,84 @@
+package estimator
+
+import (
+	"math"
+	"sort"
+)
+
+// meanToMedianDeviation converts a mean absolute deviation to the median
+// absolute deviation it matches for normally distributed data.
+const meanToMedianDeviation = 0.8453
+
+// resourceValues read each resource of a sample.
+var resourceValues = [...]func(ResourceUsage) float64{
+	func(u ResourceUsage) float64 { return u.CPU },
+	func(u ResourceUsage) float64 { return u.Memory },
+	func(u ResourceUsage) float64 { return u.GPU },
+}
+
+// filtered computes stat over samples with outliers removed, judging each
+// resource separately: the CPU of the result comes from the samples whose CPU
+// is not an outlier, and likewise for memory and GPU, so a glitch in one
+// resource does not discard a sample's other readings. Other fields come from
+// the CPU computation. With k not positive stat sees every sample.
+func filtered(samples []ResourceUsage, k float64, stat func([]ResourceUsage) ResourceUsage) ResourceUsage {
+	if k <= 0 || len(samples) < 3 {
+		return stat(samples)
+	}
+
+	result := stat(inliers(samples, resourceValues[0], k))
+	result.Memory = stat(inliers(samples, resourceValues[1], k)).Memory
+	result.GPU = stat(inliers(samples, resourceValues[2], k)).GPU
+	return result
+}
+
+// inliers returns the samples whose value lies within k median absolute
+// deviations of the median. When the median absolute deviation is zero, as
+// for a mostly steady series or a discrete resource such as GPUs, the scaled
+// mean absolute deviation stands in for it, so genuine recurring values are
+// kept and only far-off readings dropped. samples is returned as is when
+// nothing is an outlier.
+func inliers(samples []ResourceUsage, value func(ResourceUsage) float64, k float64) []ResourceUsage {
+	values := make([]float64, len(samples))
+	for i, usage := range samples {
+		values[i] = value(usage)
+	}
+	center := median(values)
+
+	var total float64
+	for i, usage := range samples {
+		values[i] = math.Abs(value(usage) - center)
+		total += values[i]
+	}
+	spread := median(values)
+	if spread == 0 {
+		spread = total / float64(len(samples)) * meanToMedianDeviation
+	}
+
+	var kept []ResourceUsage
+	for i, usage := range samples {
+		if math.Abs(value(usage)-center) <= k*spread {
+			if kept != nil {
+				kept = append(kept, usage)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = append(make([]ResourceUsage, 0, len(samples)), samples[:i]...)
+		}
+	}
+	if kept == nil {
+		return samples
+	}
+	return kept
+}
+
+// median returns the median of values, which it reorders.
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,152 @@
+package estimator
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// snapshotVersion is bumped whenever the persisted layout changes.
+const snapshotVersion = 1
+
+// snapshot is the persisted form of an Estimator's histories.
+type snapshot struct {
+	Version int
+	Groups  []groupSnapshot
+}
+
+// groupSnapshot holds one group's samples, oldest first.
+type groupSnapshot struct {
+	Namespace string
+	GroupName string
+	History   []ResourceUsage
+}
+
+// snapshot copies every group's history, sorted by key so the output is
+// stable.
+func (e *Estimator) snapshot() snapshot {
+	e.mu.RLock()
+	keys := make([]string, 0, len(e.histories))
+	for key := range e.histories {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	snap := snapshot{Version: snapshotVersion, Groups: make([]groupSnapshot, 0, len(keys))}
+	for _, key := range keys {
+		history := e.histories[key]
+		history.mu.RLock()
+		snap.Groups = append(snap.Groups, groupSnapshot{
+			Namespace: history.Namespace,
+			GroupName: history.GroupName,
+			History:   append([]ResourceUsage(nil), history.History...),
+		})
+		history.mu.RUnlock()
+	}
+	e.mu.RUnlock()
+
+	return snap
+}
+
+// SaveToFile writes every group's history to path as JSON, readable by
+// people and other tools. The file is written to a temporary file first and
+// renamed into place, so a crash never leaves a truncated snapshot behind.
+// Read it back with LoadFromFile.
+func (e *Estimator) SaveToFile(path string) error {
+	return e.save(path, func(w io.Writer, snap snapshot) error {
+		return json.NewEncoder(w).Encode(snap)
+	})
+}
+
+// SaveToFileBinary is SaveToFile in a compact gob encoding, for fleets too
+// large to persist as JSON. Read it back with LoadFromFileBinary.
+func (e *Estimator) SaveToFileBinary(path string) error {
+	return e.save(path, func(w io.Writer, snap snapshot) error {
+		return gob.NewEncoder(w).Encode(snap)
+	})
+}
+
+// save writes a snapshot to path with encode, through a temporary file.
+func (e *Estimator) save(path string, encode func(io.Writer, snapshot) error) error {
+	snap := e.snapshot()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := encode(tmp, snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	e.logger.Info("saved history", "path", path, "groups", len(snap.Groups))
+	return nil
+}
+
+// LoadFromFile reads a snapshot written by SaveToFile. Each group in it
+// replaces the estimator's history for that group, keeping the newest
+// samples if the snapshot holds more than the estimator's history size;
+// other groups are left alone.
+func (e *Estimator) LoadFromFile(path string) error {
+	return e.load(path, func(r io.Reader, snap *snapshot) error {
+		return json.NewDecoder(r).Decode(snap)
+	})
+}
+
+// LoadFromFileBinary is LoadFromFile for a snapshot written by
+// SaveToFileBinary.
+func (e *Estimator) LoadFromFileBinary(path string) error {
+	return e.load(path, func(r io.Reader, snap *snapshot) error {
+		return gob.NewDecoder(r).Decode(snap)
+	})
+}
+
+// load reads the snapshot at path with decode and swaps its groups in.
+func (e *Estimator) load(path string, decode func(io.Reader, *snapshot) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := decode(f, &snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d in %s", snap.Version, path)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, group := range snap.Groups {
+		key := fmt.Sprintf("%s/%s", group.Namespace, group.GroupName)
+		samples := group.History
+		if e.maxSize > 0 && len(samples) > e.maxSize {
+			samples = samples[len(samples)-e.maxSize:]
+		}
+
+		history := e.newHistory(group.GroupName, group.Namespace)
+		history.History = samples
+		e.histories[key] = history
+		e.cache.invalidate(key)
+		e.forgetSmoothing(key)
+		e.reportSamples(history)
+	}
+
+	e.logger.Info("loaded history", "path", path, "groups", len(snap.Groups))
+	return nil
+}
@@ -0,0 +1,127 @@
+package estimator
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimator_RoundTrip(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	est := NewEstimator(10, slog.Default())
+	est.Clock = clock
+
+	for i := 0; i < 5; i++ {
+		est.RecordUsageWithReplicas("default", "training", float64(2+i), float64(i+1)*1024*1024*1024, 1, 2)
+		est.RecordUsageWithDuration("research", "inference", 0.5, 512*1024*1024, 0.25, 30)
+		clock.Advance(time.Minute)
+	}
+
+	formats := []struct {
+		name string
+		save func(*Estimator, string) error
+		load func(*Estimator, string) error
+	}{
+		{name: "json", save: (*Estimator).SaveToFile, load: (*Estimator).LoadFromFile},
+		{name: "gob", save: (*Estimator).SaveToFileBinary, load: (*Estimator).LoadFromFileBinary},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history."+format.name)
+			require.NoError(t, format.save(est, path))
+
+			loaded := NewEstimator(10, slog.Default())
+			require.NoError(t, format.load(loaded, path))
+			assert.Equal(t, est.GroupCount(), loaded.GroupCount())
+
+			for _, key := range [][2]string{{"default", "training"}, {"research", "inference"}} {
+				want, _ := est.GetHistory(key[0], key[1])
+				got, ok := loaded.GetHistory(key[0], key[1])
+				require.True(t, ok, key)
+
+				require.Equal(t, len(want.History), len(got.History))
+				for i := range want.History {
+					w, g := want.History[i], got.History[i]
+					assert.True(t, w.Timestamp.Equal(g.Timestamp))
+					w.Timestamp, g.Timestamp = time.Time{}, time.Time{}
+					assert.Equal(t, w, g)
+				}
+				assert.Equal(t, want.GetAverage(), got.GetAverage())
+				assert.Equal(t, want.GetPeak(), got.GetPeak())
+
+				wantEstimate, err := est.EstimateResources(key[0], key[1])
+				require.NoError(t, err)
+				gotEstimate, err := loaded.EstimateResources(key[0], key[1])
+				require.NoError(t, err)
+				assert.Equal(t, wantEstimate, gotEstimate)
+			}
+		})
+	}
+}
+
+func TestEstimator_LoadFromFileBinaryKeepsNewest(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	for i := 1; i <= 10; i++ {
+		est.RecordUsage("default", "training", float64(i), 1024, 0)
+	}
+	path := filepath.Join(t.TempDir(), "history.gob")
+	require.NoError(t, est.SaveToFileBinary(path))
+
+	small := NewEstimator(3, slog.Default())
+	require.NoError(t, small.LoadFromFileBinary(path))
+
+	history, ok := small.GetHistory("default", "training")
+	require.True(t, ok)
+	assert.Equal(t, 3, history.Len())
+	assert.Equal(t, 10.0, history.GetPeak().CPU)
+
+	// Appending after a load still evicts the oldest sample.
+	small.RecordUsage("default", "training", 11, 1024, 0)
+	assert.Equal(t, 3, history.Len())
+	assert.Equal(t, 9.0, history.History[0].CPU)
+}
+
+func TestEstimator_LoadFromFileBinaryErrors(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	dir := t.TempDir()
+
+	assert.Error(t, est.LoadFromFileBinary(filepath.Join(dir, "missing.gob")))
+
+	garbage := filepath.Join(dir, "garbage.gob")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a snapshot"), 0o600))
+	err := est.LoadFromFileBinary(garbage)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode snapshot")
+	assert.Equal(t, 0, est.GroupCount())
+}
+
+func BenchmarkSaveToFileBinary(b *testing.B) {
+	est := NewEstimator(100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	est.RecordUsageBatch(benchmarkSamples(1000, 10))
+	path := filepath.Join(b.TempDir(), "history.gob")
+
+	// Report the file sizes so the gob format can be compared with JSON.
+	jsonPath := filepath.Join(b.TempDir(), "history.json")
+	require.NoError(b, est.SaveToFile(jsonPath))
+	jsonInfo, err := os.Stat(jsonPath)
+	require.NoError(b, err)
+	require.NoError(b, est.SaveToFileBinary(path))
+	info, err := os.Stat(path)
+	require.NoError(b, err)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if err := est.SaveToFileBinary(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(info.Size()), "gob-bytes")
+	b.ReportMetric(float64(jsonInfo.Size()), "json-bytes")
+}
@@ -0,0 +1,110 @@
+package estimator
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// recommendationStdDevs is how many standard deviations above the 95th
+// percentile recommended limits sit.
+const recommendationStdDevs = 2
+
+// Recommendation is a suggested request and limit for a group, with what they
+// were derived from, for surfacing in a dashboard or kubectl plugin.
+type Recommendation struct {
+	Namespace string              `json:"namespace"`
+	Group     string              `json:"group"`
+	Requests  corev1.ResourceList `json:"requests"`
+	Limits    corev1.ResourceList `json:"limits"`
+	Basis     RecommendationBasis `json:"basis"`
+
+	// Rationale explains the recommendation in a sentence or two.
+	Rationale string `json:"rationale"`
+}
+
+// RecommendationBasis is the evidence behind a Recommendation.
+type RecommendationBasis struct {
+	Samples  int    `json:"samples"`
+	Strategy string `json:"strategy"`
+
+	// Volatility is the coefficient of variation, standard deviation over
+	// mean, of each resource's usage. GPU is only included when used.
+	Volatility map[corev1.ResourceName]float64 `json:"volatility"`
+
+	// Provisional is set while the group has fewer than MinSamples samples.
+	Provisional bool `json:"provisional,omitempty"`
+}
+
+// RecommendResources recommends requests and limits for a group. Requests are
+// the strategy's estimate, as from EstimateResources. Limits are the 95th
+// percentile plus two standard deviations, so volatile groups get more
+// headroom, and never fall below the requests.
+func (e *Estimator) RecommendResources(namespace, groupName string) (*Recommendation, error) {
+	requests, err := e.EstimateResources(namespace, groupName)
+	if err != nil {
+		return nil, err
+	}
+	// The estimate may be shared with the cache.
+	requests = requests.DeepCopy()
+
+	history, exists := e.GetHistory(namespace, groupName)
+	if !exists {
+		return nil, fmt.Errorf("%w for %s/%s", ErrNoHistory, namespace, groupName)
+	}
+
+	avg := history.GetAverage()
+	stddev := history.GetStdDev()
+	p95 := history.GetPercentile(95)
+
+	limits := e.resourceList(ResourceUsage{
+		CPU:    p95.CPU + recommendationStdDevs*stddev.CPU,
+		Memory: p95.Memory + recommendationStdDevs*stddev.Memory,
+		GPU:    p95.GPU + recommendationStdDevs*stddev.GPU,
+	})
+	raiseTo(limits, requests)
+
+	basis := RecommendationBasis{
+		Samples:  history.Len(),
+		Strategy: e.strategyName,
+		Volatility: map[corev1.ResourceName]float64{
+			corev1.ResourceCPU:    volatility(avg.CPU, stddev.CPU),
+			corev1.ResourceMemory: volatility(avg.Memory, stddev.Memory),
+		},
+		Provisional: history.Len() < e.MinSamples,
+	}
+	if avg.GPU > 0 {
+		basis.Volatility[e.GPUResourceName] = volatility(avg.GPU, stddev.GPU)
+	}
+
+	return &Recommendation{
+		Namespace: namespace,
+		Group:     groupName,
+		Requests:  requests,
+		Limits:    limits,
+		Basis:     basis,
+		Rationale: rationale(basis),
+	}, nil
+}
+
+// volatility returns stddev relative to mean, or zero for an unused resource.
+func volatility(mean, stddev float64) float64 {
+	if mean <= 0 {
+		return 0
+	}
+	return stddev / mean
+}
+
+// rationale describes basis in words.
+func rationale(basis RecommendationBasis) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Requests follow the %s strategy over %d samples; limits are the 95th percentile plus %d standard deviations, and no lower than the requests.",
+		basis.Strategy, basis.Samples, recommendationStdDevs)
+	fmt.Fprintf(&b, " CPU usage varies by %.0f%% and memory by %.0f%% around the mean.",
+		basis.Volatility[corev1.ResourceCPU]*100, basis.Volatility[corev1.ResourceMemory]*100)
+	if basis.Provisional {
+		b.WriteString(" Too few samples yet; treat this as provisional.")
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package estimator
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEstimator_RecommendResources(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	est.MinSamples = 10
+	for _, cpu := range []float64{1, 2, 3, 4} {
+		est.RecordUsage("default", "training", cpu, 1024*1024*1024, 0)
+	}
+
+	rec, err := est.RecommendResources("default", "training")
+	require.NoError(t, err)
+
+	assert.Equal(t, "default", rec.Namespace)
+	assert.Equal(t, "training", rec.Group)
+
+	// 70% of the 2.5 core average plus 30% of the 4 core peak.
+	assert.Equal(t, "2950m", rec.Requests.Cpu().String())
+	assert.Equal(t, "1Gi", rec.Requests.Memory().String())
+
+	// The 4 core p95 plus twice the ~1.118 core standard deviation; steady
+	// memory gets no headroom.
+	assert.Equal(t, "6236m", rec.Limits.Cpu().String())
+	assert.Equal(t, "1Gi", rec.Limits.Memory().String())
+
+	assert.Equal(t, 4, rec.Basis.Samples)
+	assert.Equal(t, DefaultStrategy, rec.Basis.Strategy)
+	assert.InDelta(t, 0.447, rec.Basis.Volatility[corev1.ResourceCPU], 0.001)
+	assert.Zero(t, rec.Basis.Volatility[corev1.ResourceMemory])
+	assert.NotContains(t, rec.Basis.Volatility, DefaultGPUResourceName)
+	assert.True(t, rec.Basis.Provisional)
+
+	assert.Contains(t, rec.Rationale, "over 4 samples")
+	assert.Contains(t, rec.Rationale, "CPU usage varies by 45%")
+	assert.Contains(t, rec.Rationale, "provisional")
+}
+
+func TestEstimator_RecommendResourcesNoHistory(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+
+	_, err := est.RecommendResources("default", "missing")
+	assert.ErrorIs(t, err, ErrNoHistory)
+}
@@ -0,0 +1,88 @@
+package estimator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultStrategy is the strategy estimators use unless told otherwise: a
+// blend of 70% average and 30% peak usage.
+const DefaultStrategy = "weighted"
+
+// DecayedPeakStrategy blends 70% average with 30% of the peak decayed by
+// PeakHalfLife, so an old spike fades out of estimates gradually.
+const DecayedPeakStrategy = "decayed-peak"
+
+// PeakHalfLife is the half-life DecayedPeakStrategy discounts peaks by.
+const PeakHalfLife = 24 * time.Hour
+
+// Strategy turns a group's usage history into a resource estimate. Estimates
+// are returned as ResourceUsage so the Estimator can apply smoothing and its
+// GPU resource name uniformly whatever the algorithm.
+type Strategy interface {
+	Estimate(history *GroupHistory) ResourceUsage
+}
+
+// StrategyFunc adapts an ordinary function to the Strategy interface.
+type StrategyFunc func(history *GroupHistory) ResourceUsage
+
+// Estimate calls f(history).
+func (f StrategyFunc) Estimate(history *GroupHistory) ResourceUsage {
+	return f(history)
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{
+		DefaultStrategy: StrategyFunc(func(history *GroupHistory) ResourceUsage {
+			return weightedBlend(history.GetAverage(), history.GetPeak())
+		}),
+		DecayedPeakStrategy: StrategyFunc(func(history *GroupHistory) ResourceUsage {
+			return weightedBlend(history.GetAverage(), history.GetDecayedPeak(PeakHalfLife))
+		}),
+		"average": StrategyFunc(func(history *GroupHistory) ResourceUsage {
+			return history.GetAverage()
+		}),
+		"peak": StrategyFunc(func(history *GroupHistory) ResourceUsage {
+			return history.GetPeak()
+		}),
+		"p95": StrategyFunc(func(history *GroupHistory) ResourceUsage {
+			return history.GetPercentile(95)
+		}),
+	}
+)
+
+// RegisterStrategy makes strategy available to NewEstimatorWithStrategy
+// under name, replacing any strategy previously registered with that name.
+func RegisterStrategy(name string, strategy Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	strategies[name] = strategy
+}
+
+// Strategies returns the names of the registered strategies, sorted.
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupStrategy(name string) (Strategy, error) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown estimation strategy %q", name)
+	}
+	return strategy, nil
+}
@@ -0,0 +1,161 @@
+package estimator
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("test-latest", StrategyFunc(func(history *GroupHistory) ResourceUsage {
+		var latest ResourceUsage
+		history.ForEach(func(usage ResourceUsage) bool {
+			latest = usage
+			return true
+		})
+		return latest
+	}))
+	assert.Contains(t, Strategies(), "test-latest")
+	assert.Contains(t, Strategies(), DefaultStrategy)
+
+	est, err := NewEstimatorWithStrategy(10, slog.Default(), "test-latest")
+	require.NoError(t, err)
+	assert.Equal(t, "test-latest", est.StrategyName())
+
+	est.RecordUsage("default", "group", 8.0, 1024, 0)
+	est.RecordUsage("default", "group", 2.0, 1024, 0)
+
+	resources, err := est.EstimateResources("default", "group")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), resources.Cpu().MilliValue())
+
+	// The default strategy blends average and peak instead.
+	def := NewEstimator(10, slog.Default())
+	assert.Equal(t, DefaultStrategy, def.StrategyName())
+	def.RecordUsage("default", "group", 8.0, 1024, 0)
+	def.RecordUsage("default", "group", 2.0, 1024, 0)
+	resources, err = def.EstimateResources("default", "group")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5900), resources.Cpu().MilliValue())
+}
+
+func TestDecayedPeakStrategy(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	est, err := NewEstimatorWithStrategy(10, slog.Default(), DecayedPeakStrategy)
+	require.NoError(t, err)
+	est.Clock = clock
+
+	est.RecordUsage("default", "group", 16.0, 1024, 0)
+	clock.Advance(3 * PeakHalfLife)
+	est.RecordUsage("default", "group", 2.0, 1024, 0)
+	est.RecordUsage("default", "group", 3.0, 1024, 0)
+
+	// 0.7*avg(16,2,3) + 0.3*max(16/8, 3)
+	resources, err := est.EstimateResources("default", "group")
+	require.NoError(t, err)
+	assert.InDelta(t, 5800, resources.Cpu().MilliValue(), 1)
+}
+
+func TestEstimator_SetResourceStrategy(t *testing.T) {
+	est := NewEstimator(100, slog.Default())
+	require.NoError(t, est.SetResourceStrategy(corev1.ResourceCPU, "average"))
+	require.NoError(t, est.SetResourceStrategy(corev1.ResourceMemory, "p95"))
+
+	// Memory climbs 1..20 GiB while CPU alternates between 1 and 3 cores.
+	for i := 1; i <= 20; i++ {
+		est.RecordUsage("default", "group", float64(1+2*(i%2)), float64(i)*1024*1024*1024, 1)
+	}
+
+	resources, err := est.EstimateResources("default", "group")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), resources.Cpu().MilliValue())
+	assert.Equal(t, "19Gi", resources.Memory().String())
+	// GPU keeps the default weighted blend of a steady 1.
+	assert.Equal(t, int64(1), resources.Name(DefaultGPUResourceName, resource.DecimalSI).Value())
+
+	history, _ := est.GetHistory("default", "group")
+	assert.Equal(t, 19.0*1024*1024*1024, history.GetPercentile(95).Memory)
+
+	err = est.SetResourceStrategy(corev1.ResourceEphemeralStorage, "p95")
+	assert.ErrorIs(t, err, ErrUnknownResource)
+	err = est.SetResourceStrategy(corev1.ResourceCPU, "no-such-strategy")
+	assert.EqualError(t, err, `unknown estimation strategy "no-such-strategy"`)
+}
+
+func TestNewEstimatorWithStrategy_Unknown(t *testing.T) {
+	est, err := NewEstimatorWithStrategy(10, slog.Default(), "no-such-strategy")
+	assert.Nil(t, est)
+	assert.EqualError(t, err, `unknown estimation strategy "no-such-strategy"`)
+}
+
+func TestEstimator_EstimateDetailed(t *testing.T) {
+	RegisterStrategy("test-peak", StrategyFunc(func(history *GroupHistory) ResourceUsage {
+		return history.GetPeak()
+	}))
+	est, err := NewEstimatorWithStrategy(10, slog.Default(), "test-peak")
+	require.NoError(t, err)
+
+	_, _, err = est.EstimateDetailed("default", "group")
+	assert.EqualError(t, err, "no history found for default/group")
+
+	est.RecordUsage("default", "group", 2.0, 1024, 0)
+	est.RecordUsage("default", "group", 4.0, 2048, 1)
+	est.RecordUsage("default", "group", 6.0, 3072, 0)
+
+	resources, details, err := est.EstimateDetailed("default", "group")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6000), resources.Cpu().MilliValue())
+	assert.Equal(t, "test-peak", details.Strategy)
+	assert.Equal(t, 3, details.Samples)
+	assert.Equal(t, 4.0, details.Average.CPU)
+	assert.Equal(t, 2048.0, details.Average.Memory)
+	assert.Equal(t, 6.0, details.Peak.CPU)
+	assert.Equal(t, 1.0, details.Peak.GPU)
+}
+
+func TestEstimator_EstimateDetailedPerResourceStrategies(t *testing.T) {
+	est := NewEstimator(10, slog.Default())
+	require.NoError(t, est.SetResourceStrategy(corev1.ResourceMemory, "peak"))
+	est.RecordUsage("default", "group", 2.0, 1024, 0)
+	est.RecordUsage("default", "group", 4.0, 2048, 0)
+
+	resources, details, err := est.EstimateDetailed("default", "group")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStrategy, details.Strategy)
+	assert.Equal(t, map[corev1.ResourceName]string{
+		corev1.ResourceCPU:     DefaultStrategy,
+		corev1.ResourceMemory:  "peak",
+		DefaultGPUResourceName: DefaultStrategy,
+	}, details.Strategies)
+	assert.Equal(t, "2Ki", resources.Memory().String())
+}
+
+func TestEstimator_EstimateDetailedConsistent(t *testing.T) {
+	est := NewEstimator(1000, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	est.RecordUsage("default", "group", 1, 1024, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 2; i <= 500; i++ {
+			est.RecordUsage("default", "group", float64(i), 1024, 0)
+		}
+	}()
+
+	// The estimate always matches the weighted blend of the reported
+	// average and peak, however many samples land in between.
+	for range 200 {
+		resources, details, err := est.EstimateDetailed("default", "group")
+		require.NoError(t, err)
+		want := weightedBlend(details.Average, details.Peak)
+		assert.Equal(t, int64(math.Round(want.CPU*1000)), resources.Cpu().MilliValue(), "%d samples", details.Samples)
+	}
+	<-done
+}
@@ -0,0 +1,146 @@
+// Package events provides an in-memory event bus for scheduler components.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// Event is a message delivered to subscribers of its type.
+type Event struct {
+	Type      string
+	Payload   any
+	Timestamp time.Time
+}
+
+// Bus is an in-memory publish/subscribe event bus. Every subscriber has its
+// own buffered channel; publishing never blocks, and an event is dropped for
+// any subscriber whose buffer is full.
+type Bus struct {
+	subscribers map[string][]chan Event // key: event type
+	mu          sync.RWMutex
+	bufferSize  int
+	collector   *metrics.Collector
+	logger      *slog.Logger
+}
+
+// NewBus creates an event bus whose subscribers buffer up to bufferSize
+// events. The collector is optional and, when set, receives published and
+// dropped counts and the total number of buffered events.
+func NewBus(bufferSize int, collector *metrics.Collector, logger *slog.Logger) *Bus {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Bus{
+		subscribers: make(map[string][]chan Event),
+		bufferSize:  bufferSize,
+		collector:   collector,
+		logger:      logger,
+	}
+}
+
+// Subscribe returns a channel receiving every event of eventType published
+// after the call. When ctx is cancelled the subscription is removed and the
+// channel closed once; events buffered before then can still be received.
+func (b *Bus) Subscribe(ctx context.Context, eventType string) <-chan Event {
+	ch := make(chan Event, b.bufferSize)
+
+	b.mu.Lock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], ch)
+	b.mu.Unlock()
+
+	context.AfterFunc(ctx, func() {
+		b.unsubscribe(eventType, ch)
+	})
+
+	b.logger.Debug("subscribed to events", "type", eventType)
+	return ch
+}
+
+// SubscriberCount returns the number of live subscriptions to eventType.
+func (b *Bus) SubscriberCount(eventType string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers[eventType])
+}
+
+// unsubscribe removes ch from the subscribers of eventType and closes it.
+func (b *Bus) unsubscribe(eventType string, ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscribers := b.subscribers[eventType]
+	for i, sub := range subscribers {
+		if sub != ch {
+			continue
+		}
+		subscribers = append(subscribers[:i], subscribers[i+1:]...)
+		break
+	}
+
+	if len(subscribers) == 0 {
+		delete(b.subscribers, eventType)
+	} else {
+		b.subscribers[eventType] = subscribers
+	}
+	close(ch)
+
+	if b.collector != nil {
+		b.collector.SetEventBusBufferSize(float64(b.buffered()))
+	}
+
+	b.logger.Debug("unsubscribed from events", "type", eventType)
+}
+
+// Publish delivers an event to all subscribers of eventType without blocking.
+func (b *Bus) Publish(eventType string, payload any) {
+	event := Event{
+		Type:      eventType,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	subscribers := b.subscribers[eventType]
+	dropped := 0
+	for _, ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		b.logger.Warn("dropped events for slow subscribers", "type", eventType, "dropped", dropped)
+	}
+
+	if b.collector != nil {
+		b.collector.IncEventsPublished(eventType)
+		b.collector.AddEventDeliveries(eventType, len(subscribers))
+		for i := 0; i < dropped; i++ {
+			b.collector.IncEventsDropped(eventType)
+		}
+		b.collector.SetEventBusBufferSize(float64(b.buffered()))
+	}
+}
+
+// buffered returns the number of events waiting in subscriber buffers.
+// Callers must hold b.mu.
+func (b *Bus) buffered() int {
+	total := 0
+	for _, subscribers := range b.subscribers {
+		for _, ch := range subscribers {
+			total += len(ch)
+		}
+	}
+	return total
+}
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// metricValue returns the value of the series in family name whose "type"
+// label matches eventType, or the unlabeled series when eventType is empty.
+func metricValue(t *testing.T, collector *metrics.Collector, name, eventType string) float64 {
+	t.Helper()
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if eventType == "" || hasLabel(metric, "type", eventType) {
+				if metric.GetCounter() != nil {
+					return metric.GetCounter().GetValue()
+				}
+				return metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func hasLabel(metric *dto.Metric, name, value string) bool {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name && label.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus(10, nil, slog.Default())
+
+	ready := bus.Subscribe(context.Background(), "GroupReady")
+	other := bus.Subscribe(context.Background(), "PodAdded")
+
+	bus.Publish("GroupReady", "default/group1")
+
+	select {
+	case event := <-ready:
+		assert.Equal(t, "GroupReady", event.Type)
+		assert.Equal(t, "default/group1", event.Payload)
+		assert.False(t, event.Timestamp.IsZero())
+	default:
+		t.Fatal("expected a GroupReady event")
+	}
+
+	assert.Empty(t, other)
+}
+
+func TestBus_FanOut(t *testing.T) {
+	bus := NewBus(10, nil, slog.Default())
+
+	first := bus.Subscribe(context.Background(), "GroupReady")
+	second := bus.Subscribe(context.Background(), "GroupReady")
+
+	bus.Publish("GroupReady", 1)
+
+	assert.Len(t, first, 1)
+	assert.Len(t, second, 1)
+}
+
+func TestBus_DropsWhenFull(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default())
+	bus := NewBus(2, collector, slog.Default())
+
+	publishedBefore := metricValue(t, collector, "volcano_events_published_total", "DropTest")
+	droppedBefore := metricValue(t, collector, "volcano_events_dropped_total", "DropTest")
+
+	events := bus.Subscribe(context.Background(), "DropTest")
+	for i := 0; i < 5; i++ {
+		bus.Publish("DropTest", i)
+	}
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, 0, (<-events).Payload)
+
+	assert.Equal(t, publishedBefore+5, metricValue(t, collector, "volcano_events_published_total", "DropTest"))
+	assert.Equal(t, droppedBefore+3, metricValue(t, collector, "volcano_events_dropped_total", "DropTest"))
+	assert.Equal(t, 2.0, metricValue(t, collector, "volcano_event_bus_buffer_size", ""))
+}
+
+func TestBus_DropRatioWithFanOut(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default())
+	bus := NewBus(1, collector, slog.Default())
+
+	// Two subscribers, neither reading: the first of three events reaches
+	// both, the other two are dropped by both.
+	bus.Subscribe(context.Background(), "FanOutDropTest")
+	bus.Subscribe(context.Background(), "FanOutDropTest")
+	for i := 0; i < 3; i++ {
+		bus.Publish("FanOutDropTest", i)
+	}
+
+	// Four of six deliveries were dropped; per publish it would be 4/3.
+	assert.InDelta(t, 4.0/6, collector.EventDropRatio("FanOutDropTest"), 1e-9)
+	assert.InDelta(t, 4.0/6, metricValue(t, collector, "volcano_events_drop_ratio", "FanOutDropTest"), 1e-9)
+}
+
+func TestBus_SubscribeCancel(t *testing.T) {
+	bus := NewBus(10, nil, slog.Default())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := bus.Subscribe(ctx, "GroupReady")
+	remaining := bus.Subscribe(context.Background(), "GroupReady")
+	require.Equal(t, 2, bus.SubscriberCount("GroupReady"))
+
+	bus.Publish("GroupReady", 1)
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return bus.SubscriberCount("GroupReady") == 1
+	}, time.Second, time.Millisecond)
+
+	// The channel closes after yielding the event buffered before cancel.
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, 1, received)
+
+	bus.Publish("GroupReady", 2)
+	assert.Len(t, remaining, 2)
+}
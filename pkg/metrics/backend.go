@@ -0,0 +1,73 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Labels are the label names and values of one metric series.
+type Labels map[string]string
+
+// Backend receives the metric updates a Collector makes, identified by the
+// Prometheus metric name, e.g. "volcano_groups_total", and the series
+// labels; unlabeled metrics get nil labels. The default backend is the
+// collector's own Prometheus registry. Implementations must be safe for
+// concurrent use and should ignore names they do not know.
+type Backend interface {
+	// SetGauge sets a gauge to value.
+	SetGauge(name string, labels Labels, value float64)
+	// AddGauge adds delta, which may be negative, to a gauge.
+	AddGauge(name string, labels Labels, delta float64)
+	// IncCounter adds one to a counter.
+	IncCounter(name string, labels Labels)
+	// Observe records value in a histogram or summary.
+	Observe(name string, labels Labels, value float64)
+	// DeleteSeries drops one labeled series.
+	DeleteSeries(name string, labels Labels)
+}
+
+// SetGauge implements Backend.
+func (m *metricSet) SetGauge(name string, labels Labels, value float64) {
+	switch metric := m.byName[name].(type) {
+	case *prometheus.GaugeVec:
+		metric.With(prometheus.Labels(labels)).Set(value)
+	case prometheus.Gauge:
+		metric.Set(value)
+	}
+}
+
+// AddGauge implements Backend.
+func (m *metricSet) AddGauge(name string, labels Labels, delta float64) {
+	switch metric := m.byName[name].(type) {
+	case *prometheus.GaugeVec:
+		metric.With(prometheus.Labels(labels)).Add(delta)
+	case prometheus.Gauge:
+		metric.Add(delta)
+	}
+}
+
+// IncCounter implements Backend.
+func (m *metricSet) IncCounter(name string, labels Labels) {
+	switch metric := m.byName[name].(type) {
+	case *prometheus.CounterVec:
+		metric.With(prometheus.Labels(labels)).Inc()
+	case prometheus.Counter:
+		metric.Inc()
+	}
+}
+
+// Observe implements Backend.
+func (m *metricSet) Observe(name string, labels Labels, value float64) {
+	switch metric := m.byName[name].(type) {
+	case *prometheus.HistogramVec:
+		metric.With(prometheus.Labels(labels)).Observe(value)
+	case *prometheus.SummaryVec:
+		metric.With(prometheus.Labels(labels)).Observe(value)
+	case prometheus.Observer:
+		metric.Observe(value)
+	}
+}
+
+// DeleteSeries implements Backend.
+func (m *metricSet) DeleteSeries(name string, labels Labels) {
+	if metric, ok := m.byName[name].(interface{ Delete(prometheus.Labels) bool }); ok {
+		metric.Delete(prometheus.Labels(labels))
+	}
+}
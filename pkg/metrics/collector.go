@@ -3,223 +3,545 @@ package metrics
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var (
-	once     sync.Once
+	defaultOnce    sync.Once
+	defaultMetrics *metricSet
+)
+
+// metricSet is one instance of every metric together with the registry that
+// serves them.
+type metricSet struct {
 	registry *prometheus.Registry
 
+	// byName indexes the registered metrics for the Backend methods.
+	byName map[string]prometheus.Collector
+
 	// Group metrics
-	groupsTotal = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "volcano_groups_total",
-			Help: "Total number of job groups by state",
-		},
-		[]string{"state"},
-	)
-
-	groupReadyDuration = prometheus.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "volcano_group_ready_duration_seconds",
-			Help:    "Time taken for a group to become ready",
-			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
-		},
-	)
-
-	groupTimeouts = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "volcano_group_timeouts_total",
-			Help: "Total number of group timeouts",
-		},
-	)
-
-	groupPodsGauge = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "volcano_group_pods",
-			Help: "Number of pods in groups by phase",
-		},
-		[]string{"group", "namespace", "phase"},
-	)
+	groupsTotal               *prometheus.GaugeVec
+	groupReadyDuration        prometheus.Histogram
+	groupReadyDurationSummary prometheus.Summary
+	groupReadyDurationByQueue *prometheus.HistogramVec
+	groupTimeouts             prometheus.Counter
+	groupPodsGauge            *prometheus.GaugeVec
 
 	// Quota metrics
-	quotaAllocated = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "volcano_quota_allocated",
-			Help: "Allocated quota by namespace and resource",
-		},
-		[]string{"namespace", "resource"},
-	)
-
-	quotaAvailable = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "volcano_quota_available",
-			Help: "Available quota by namespace and resource",
-		},
-		[]string{"namespace", "resource"},
-	)
-
-	quotaBorrowed = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "volcano_quota_borrowed",
-			Help: "Borrowed quota by namespace and resource",
-		},
-		[]string{"namespace", "resource"},
-	)
-
-	quotaPreemptions = prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: "volcano_quota_preemptions_total",
-			Help: "Total number of quota preemptions",
-		},
-	)
+	quotaAllocated   *prometheus.GaugeVec
+	quotaAvailable   *prometheus.GaugeVec
+	quotaBorrowed    *prometheus.GaugeVec
+	quotaPreemptions *prometheus.CounterVec
 
 	// Event bus metrics
-	eventsPublished = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "volcano_events_published_total",
-			Help: "Total number of events published by type",
-		},
-		[]string{"type"},
-	)
-
-	eventsDropped = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "volcano_events_dropped_total",
-			Help: "Total number of events dropped by type",
-		},
-		[]string{"type"},
-	)
-
-	eventBusBufferSize = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "volcano_event_bus_buffer_size",
-			Help: "Current size of event bus buffer",
-		},
-	)
+	eventsPublished    *prometheus.CounterVec
+	eventsDropped      *prometheus.CounterVec
+	eventsDropRatio    *prometheus.GaugeVec
+	eventBusBufferSize prometheus.Gauge
+
+	// eventCounts tracks attempted and dropped deliveries per event type so
+	// the drop ratio can be derived.
+	eventCountsMu sync.Mutex
+	eventCounts   map[string]*eventCount
 
 	// Scheduler metrics
-	schedulingAttempts = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "volcano_scheduling_attempts_total",
-			Help: "Total scheduling attempts by result",
-		},
-		[]string{"result"},
-	)
-
-	schedulingLatency = prometheus.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "volcano_scheduling_latency_seconds",
-			Help:    "Scheduling decision latency",
-			Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
-		},
-	)
-)
+	schedulingAttempts *prometheus.CounterVec
+	schedulingLatency  prometheus.Histogram
+
+	// Webhook metrics
+	webhookRequests           *prometheus.CounterVec
+	webhookParseErrors        *prometheus.CounterVec
+	webhookPatchBytes         prometheus.Histogram
+	webhookCertReloads        prometheus.Counter
+	webhookCertReloadFailures prometheus.Counter
+	webhookInflightRequests   prometheus.Gauge
+
+	// Estimator metrics
+	estimatorGroupSamples *prometheus.GaugeVec
+}
+
+func newMetricSet(cfg collectorConfig) *metricSet {
+	m := &metricSet{
+		registry:    prometheus.NewRegistry(),
+		byName:      make(map[string]prometheus.Collector),
+		eventCounts: make(map[string]*eventCount),
+
+		groupsTotal: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_groups_total",
+				Help: "Total number of job groups by state",
+			},
+			[]string{"state"},
+		),
+
+		groupReadyDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "volcano_group_ready_duration_seconds",
+				Help:    "Time taken for a group to become ready",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+			},
+		),
+
+		groupReadyDurationSummary: prometheus.NewSummary(
+			prometheus.SummaryOpts{
+				Name:       "volcano_group_ready_duration_summary_seconds",
+				Help:       "Quantiles of the time taken for a group to become ready",
+				Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			},
+		),
+
+		groupReadyDurationByQueue: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "volcano_group_ready_duration_by_queue_seconds",
+				Help:    "Time taken for a group to become ready by queue",
+				Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+			},
+			[]string{"queue"},
+		),
+
+		groupTimeouts: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "volcano_group_timeouts_total",
+				Help: "Total number of group timeouts",
+			},
+		),
+
+		groupPodsGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_group_pods",
+				Help: "Number of pods in groups by phase",
+			},
+			[]string{"group", "namespace", "phase"},
+		),
+
+		quotaAllocated: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_quota_allocated",
+				Help: "Allocated quota by namespace and resource",
+			},
+			[]string{"namespace", "resource"},
+		),
+
+		quotaAvailable: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_quota_available",
+				Help: "Available quota by namespace and resource",
+			},
+			[]string{"namespace", "resource"},
+		),
+
+		quotaBorrowed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_quota_borrowed",
+				Help: "Borrowed quota by namespace and resource",
+			},
+			[]string{"namespace", "resource"},
+		),
+
+		quotaPreemptions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_quota_preemptions_total",
+				Help: "Total number of quota preemptions by victim and beneficiary namespace",
+			},
+			[]string{"from_namespace", "to_namespace"},
+		),
+
+		eventsPublished: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_events_published_total",
+				Help: "Total number of events published by type",
+			},
+			[]string{"type"},
+		),
+
+		eventsDropped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_events_dropped_total",
+				Help: "Total number of events dropped by type",
+			},
+			[]string{"type"},
+		),
+
+		eventsDropRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_events_drop_ratio",
+				Help: "Dropped deliveries per attempted delivery to a subscriber, by type",
+			},
+			[]string{"type"},
+		),
+
+		eventBusBufferSize: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "volcano_event_bus_buffer_size",
+				Help: "Current size of event bus buffer",
+			},
+		),
+
+		schedulingAttempts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_scheduling_attempts_total",
+				Help: "Total scheduling attempts by result and failure reason",
+			},
+			[]string{"result", "reason"},
+		),
+
+		schedulingLatency: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "volcano_scheduling_latency_seconds",
+				Help:    "Scheduling decision latency",
+				Buckets: cfg.schedulingLatencyBuckets,
+			},
+		),
+
+		webhookRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_webhook_requests_total",
+				Help: "Total admission requests by path, operation and dry-run",
+			},
+			[]string{"path", "operation", "dry_run"},
+		),
+
+		webhookParseErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "volcano_webhook_parse_errors_total",
+				Help: "Total admission reviews that failed to parse by path",
+			},
+			[]string{"path"},
+		),
+
+		webhookPatchBytes: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Name:    "volcano_webhook_patch_bytes",
+				Help:    "Size of patches produced by the mutating webhook",
+				Buckets: prometheus.ExponentialBuckets(64, 2, 10),
+			},
+		),
+
+		webhookCertReloads: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "volcano_webhook_cert_reloads_total",
+				Help: "Total TLS certificate loads by the webhook",
+			},
+		),
+
+		webhookCertReloadFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "volcano_webhook_cert_reload_failures_total",
+				Help: "Total failed TLS certificate loads by the webhook",
+			},
+		),
+
+		webhookInflightRequests: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "volcano_webhook_inflight_requests",
+				Help: "Admission requests currently being handled by the webhook",
+			},
+		),
+
+		estimatorGroupSamples: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "volcano_estimator_group_samples",
+				Help: "Usage samples held by the estimator by group",
+			},
+			[]string{"namespace", "group"},
+		),
+	}
+
+	// Register all metrics but the disabled ones, whose fields are cleared
+	// so their update methods do nothing.
+	for _, metric := range []struct {
+		name      string
+		collector prometheus.Collector
+		disable   func()
+	}{
+		{"volcano_groups_total", m.groupsTotal, func() { m.groupsTotal = nil }},
+		{"volcano_group_ready_duration_seconds", m.groupReadyDuration, func() { m.groupReadyDuration = nil }},
+		{"volcano_group_ready_duration_summary_seconds", m.groupReadyDurationSummary, func() { m.groupReadyDurationSummary = nil }},
+		{"volcano_group_ready_duration_by_queue_seconds", m.groupReadyDurationByQueue, func() { m.groupReadyDurationByQueue = nil }},
+		{"volcano_group_timeouts_total", m.groupTimeouts, func() { m.groupTimeouts = nil }},
+		{"volcano_group_pods", m.groupPodsGauge, func() { m.groupPodsGauge = nil }},
+		{"volcano_quota_allocated", m.quotaAllocated, func() { m.quotaAllocated = nil }},
+		{"volcano_quota_available", m.quotaAvailable, func() { m.quotaAvailable = nil }},
+		{"volcano_quota_borrowed", m.quotaBorrowed, func() { m.quotaBorrowed = nil }},
+		{"volcano_quota_preemptions_total", m.quotaPreemptions, func() { m.quotaPreemptions = nil }},
+		{"volcano_events_published_total", m.eventsPublished, func() { m.eventsPublished = nil }},
+		{"volcano_events_dropped_total", m.eventsDropped, func() { m.eventsDropped = nil }},
+		{"volcano_events_drop_ratio", m.eventsDropRatio, func() { m.eventsDropRatio = nil }},
+		{"volcano_event_bus_buffer_size", m.eventBusBufferSize, func() { m.eventBusBufferSize = nil }},
+		{"volcano_scheduling_attempts_total", m.schedulingAttempts, func() { m.schedulingAttempts = nil }},
+		{"volcano_scheduling_latency_seconds", m.schedulingLatency, func() { m.schedulingLatency = nil }},
+		{"volcano_webhook_requests_total", m.webhookRequests, func() { m.webhookRequests = nil }},
+		{"volcano_webhook_parse_errors_total", m.webhookParseErrors, func() { m.webhookParseErrors = nil }},
+		{"volcano_webhook_patch_bytes", m.webhookPatchBytes, func() { m.webhookPatchBytes = nil }},
+		{"volcano_webhook_cert_reloads_total", m.webhookCertReloads, func() { m.webhookCertReloads = nil }},
+		{"volcano_webhook_cert_reload_failures_total", m.webhookCertReloadFailures, func() { m.webhookCertReloadFailures = nil }},
+		{"volcano_webhook_inflight_requests", m.webhookInflightRequests, func() { m.webhookInflightRequests = nil }},
+		{"volcano_estimator_group_samples", m.estimatorGroupSamples, func() { m.estimatorGroupSamples = nil }},
+	} {
+		if cfg.backend != nil || cfg.disabled[metric.name] {
+			metric.disable()
+			continue
+		}
+		m.registry.MustRegister(metric.collector)
+		m.byName[metric.name] = metric.collector
+	}
+
+	return m
+}
 
 // Collector provides methods to update metrics.
 type Collector struct {
 	logger *slog.Logger
-}
+	*metricSet
 
-// NewCollector creates a new metrics collector.
-func NewCollector(logger *slog.Logger) *Collector {
-	once.Do(func() {
-		registry = prometheus.NewRegistry()
-
-		// Register all metrics
-		registry.MustRegister(
-			groupsTotal,
-			groupReadyDuration,
-			groupTimeouts,
-			groupPodsGauge,
-			quotaAllocated,
-			quotaAvailable,
-			quotaBorrowed,
-			quotaPreemptions,
-			eventsPublished,
-			eventsDropped,
-			eventBusBufferSize,
-			schedulingAttempts,
-			schedulingLatency,
-		)
-	})
+	// backend receives every update: the metricSet itself unless WithBackend
+	// supplied another.
+	backend Backend
+}
 
+// NewCollector creates a new metrics collector. Collectors created without
+// options share one process-wide set of metrics; options give the collector
+// its own metrics and registry, or with WithBackend, send updates elsewhere.
+func NewCollector(logger *slog.Logger, opts ...Option) *Collector {
 	if logger == nil {
 		logger = slog.Default()
 	}
 
-	return &Collector{logger: logger}
+	if len(opts) == 0 {
+		defaultOnce.Do(func() {
+			defaultMetrics = newMetricSet(defaultCollectorConfig())
+		})
+		return &Collector{logger: logger, metricSet: defaultMetrics, backend: defaultMetrics}
+	}
+
+	cfg := defaultCollectorConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m := newMetricSet(cfg)
+	if cfg.backend != nil {
+		return &Collector{logger: logger, metricSet: m, backend: cfg.backend}
+	}
+	return &Collector{logger: logger, metricSet: m, backend: m}
 }
 
 // Group metrics methods
 func (c *Collector) SetGroupsTotal(state string, count float64) {
-	groupsTotal.WithLabelValues(state).Set(count)
+	c.backend.SetGauge("volcano_groups_total", Labels{"state": state}, count)
 }
 
 func (c *Collector) ObserveGroupReadyDuration(seconds float64) {
-	groupReadyDuration.Observe(seconds)
+	c.backend.Observe("volcano_group_ready_duration_seconds", nil, seconds)
+	c.backend.Observe("volcano_group_ready_duration_summary_seconds", nil, seconds)
+}
+
+// ObserveGroupReadyDurationByQueue records how long a group in queue took to
+// become ready. It does not update the unlabeled ready duration metrics.
+func (c *Collector) ObserveGroupReadyDurationByQueue(queue string, seconds float64) {
+	c.backend.Observe("volcano_group_ready_duration_by_queue_seconds", Labels{"queue": queue}, seconds)
 }
 
 func (c *Collector) IncGroupTimeouts() {
-	groupTimeouts.Inc()
+	c.backend.IncCounter("volcano_group_timeouts_total", nil)
 }
 
 func (c *Collector) SetGroupPods(group, namespace, phase string, count float64) {
-	groupPodsGauge.WithLabelValues(group, namespace, phase).Set(count)
+	c.backend.SetGauge("volcano_group_pods", Labels{"group": group, "namespace": namespace, "phase": phase}, count)
+}
+
+// IncGroupPods adds a pod to the group's count for phase, for callers that
+// see pod events rather than snapshots. It shares the SetGroupPods gauge.
+func (c *Collector) IncGroupPods(group, namespace, phase string) {
+	c.backend.AddGauge("volcano_group_pods", Labels{"group": group, "namespace": namespace, "phase": phase}, 1)
+}
+
+// DecGroupPods removes a pod from the group's count for phase.
+func (c *Collector) DecGroupPods(group, namespace, phase string) {
+	c.backend.AddGauge("volcano_group_pods", Labels{"group": group, "namespace": namespace, "phase": phase}, -1)
 }
 
 // Quota metrics methods
 func (c *Collector) SetQuotaAllocated(namespace, resource string, value float64) {
-	quotaAllocated.WithLabelValues(namespace, resource).Set(value)
+	c.backend.SetGauge("volcano_quota_allocated", Labels{"namespace": namespace, "resource": resource}, value)
 }
 
 func (c *Collector) SetQuotaAvailable(namespace, resource string, value float64) {
-	quotaAvailable.WithLabelValues(namespace, resource).Set(value)
+	c.backend.SetGauge("volcano_quota_available", Labels{"namespace": namespace, "resource": resource}, value)
 }
 
 func (c *Collector) SetQuotaBorrowed(namespace, resource string, value float64) {
-	quotaBorrowed.WithLabelValues(namespace, resource).Set(value)
+	c.backend.SetGauge("volcano_quota_borrowed", Labels{"namespace": namespace, "resource": resource}, value)
 }
 
 func (c *Collector) IncQuotaPreemptions() {
-	quotaPreemptions.Inc()
+	c.IncQuotaPreemptionsBetween("", "")
+}
+
+// IncQuotaPreemptionsBetween counts a preemption of quota borrowed by
+// fromNamespace, reclaimed for toNamespace.
+func (c *Collector) IncQuotaPreemptionsBetween(fromNamespace, toNamespace string) {
+	c.backend.IncCounter("volcano_quota_preemptions_total", Labels{"from_namespace": fromNamespace, "to_namespace": toNamespace})
 }
 
 // Event metrics methods
 func (c *Collector) IncEventsPublished(eventType string) {
-	eventsPublished.WithLabelValues(eventType).Inc()
+	c.backend.IncCounter("volcano_events_published_total", Labels{"type": eventType})
 }
 
+// IncEventsDropped counts an event of eventType that one subscriber missed
+// because its buffer was full. An event missed by several subscribers counts
+// once for each.
 func (c *Collector) IncEventsDropped(eventType string) {
-	eventsDropped.WithLabelValues(eventType).Inc()
+	c.backend.IncCounter("volcano_events_dropped_total", Labels{"type": eventType})
+	c.updateEventCounts(eventType, 0, 1)
+}
+
+// AddEventDeliveries counts n attempted deliveries of an event of eventType,
+// one per subscriber it was published to. They are the denominator of the
+// drop ratio, so it stays between 0 and 1 however many subscribers there are.
+func (c *Collector) AddEventDeliveries(eventType string, n int) {
+	c.updateEventCounts(eventType, float64(n), 0)
+}
+
+// EventDropRatio returns dropped deliveries per attempted delivery of
+// eventType, or 0 before any event of that type was delivered.
+func (c *Collector) EventDropRatio(eventType string) float64 {
+	c.eventCountsMu.Lock()
+	defer c.eventCountsMu.Unlock()
+
+	if counts, ok := c.eventCounts[eventType]; ok {
+		return counts.ratio()
+	}
+	return 0
+}
+
+type eventCount struct {
+	deliveries float64
+	dropped    float64
+}
+
+func (e *eventCount) ratio() float64 {
+	if e.deliveries == 0 {
+		return 0
+	}
+	return e.dropped / e.deliveries
+}
+
+// updateEventCounts adds to the counts for eventType and refreshes its drop
+// ratio gauge.
+func (c *Collector) updateEventCounts(eventType string, deliveries, dropped float64) {
+	c.eventCountsMu.Lock()
+	defer c.eventCountsMu.Unlock()
+
+	counts, ok := c.eventCounts[eventType]
+	if !ok {
+		counts = &eventCount{}
+		c.eventCounts[eventType] = counts
+	}
+	counts.deliveries += deliveries
+	counts.dropped += dropped
+
+	c.backend.SetGauge("volcano_events_drop_ratio", Labels{"type": eventType}, counts.ratio())
 }
 
 func (c *Collector) SetEventBusBufferSize(size float64) {
-	eventBusBufferSize.Set(size)
+	c.backend.SetGauge("volcano_event_bus_buffer_size", nil, size)
 }
 
 // Scheduler metrics methods
 func (c *Collector) IncSchedulingAttempts(result string) {
-	schedulingAttempts.WithLabelValues(result).Inc()
+	c.IncSchedulingAttemptsWithReason(result, "")
+}
+
+// IncSchedulingAttemptsWithReason counts an attempt along with why it failed,
+// e.g. "insufficient-quota", "timeout" or "node-unfit". Successful attempts
+// use an empty reason.
+func (c *Collector) IncSchedulingAttemptsWithReason(result, reason string) {
+	c.backend.IncCounter("volcano_scheduling_attempts_total", Labels{"result": result, "reason": reason})
 }
 
 func (c *Collector) ObserveSchedulingLatency(seconds float64) {
-	schedulingLatency.Observe(seconds)
+	c.backend.Observe("volcano_scheduling_latency_seconds", nil, seconds)
+}
+
+// Webhook metrics methods
+
+// IncWebhookRequests counts an admission request to path by its operation,
+// e.g. "CREATE", and whether it is a dry run such as from kubectl diff.
+func (c *Collector) IncWebhookRequests(path, operation string, dryRun bool) {
+	c.backend.IncCounter("volcano_webhook_requests_total", Labels{
+		"path":      path,
+		"operation": operation,
+		"dry_run":   strconv.FormatBool(dryRun),
+	})
+}
+
+func (c *Collector) IncWebhookParseErrors(path string) {
+	c.backend.IncCounter("volcano_webhook_parse_errors_total", Labels{"path": path})
+}
+
+func (c *Collector) ObserveMutationPatchSize(bytes int) {
+	c.backend.Observe("volcano_webhook_patch_bytes", nil, float64(bytes))
+}
+
+func (c *Collector) IncWebhookCertReloads() {
+	c.backend.IncCounter("volcano_webhook_cert_reloads_total", nil)
+}
+
+func (c *Collector) IncWebhookCertReloadFailures() {
+	c.backend.IncCounter("volcano_webhook_cert_reload_failures_total", nil)
+}
+
+func (c *Collector) IncWebhookInflightRequests() {
+	c.backend.AddGauge("volcano_webhook_inflight_requests", nil, 1)
+}
+
+func (c *Collector) DecWebhookInflightRequests() {
+	c.backend.AddGauge("volcano_webhook_inflight_requests", nil, -1)
+}
+
+// Estimator metrics methods
+func (c *Collector) SetEstimatorGroupSamples(namespace, group string, count int) {
+	c.backend.SetGauge("volcano_estimator_group_samples", Labels{"namespace": namespace, "group": group}, float64(count))
+}
+
+// DeleteEstimatorGroupSamples drops the series of a group the estimator no
+// longer tracks.
+func (c *Collector) DeleteEstimatorGroupSamples(namespace, group string) {
+	c.backend.DeleteSeries("volcano_estimator_group_samples", Labels{"namespace": namespace, "group": group})
+}
+
+// Gather returns the current value of every registered metric, for in-process
+// inspection without scraping.
+func (c *Collector) Gather() ([]*dto.MetricFamily, error) {
+	return c.registry.Gather()
+}
+
+// MetricsHandler returns a handler serving the collector's metrics in the
+// Prometheus exposition format, for mounting at /metrics on another server.
+func (c *Collector) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
 }
 
 // ServeMetrics starts HTTP server for Prometheus metrics.
 func (c *Collector) ServeMetrics(addr string) error {
 	c.logger.Info("starting metrics server", "addr", addr)
+	return http.ListenAndServe(addr, c.handler())
+}
 
+// handler returns the metrics server's routes.
+func (c *Collector) handler() http.Handler {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.Handle("/metrics", c.MetricsHandler())
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-
-	return http.ListenAndServe(addr, mux)
+	return mux
 }
@@ -1,10 +1,19 @@
 package metrics
 
 import (
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewCollector(t *testing.T) {
@@ -26,6 +35,20 @@ func TestGroupMetrics(t *testing.T) {
 	assert.NotNil(t, collector)
 }
 
+func TestGroupPodsDeltas(t *testing.T) {
+	collector := NewCollector(slog.Default())
+	gauge := collector.groupPodsGauge.WithLabelValues("delta-group", "default", "Running")
+
+	collector.SetGroupPods("delta-group", "default", "Running", 2)
+	collector.IncGroupPods("delta-group", "default", "Running")
+	collector.IncGroupPods("delta-group", "default", "Running")
+	collector.DecGroupPods("delta-group", "default", "Running")
+	assert.Equal(t, 3.0, testutil.ToFloat64(gauge))
+
+	collector.DecGroupPods("delta-group", "default", "Pending")
+	assert.Equal(t, -1.0, testutil.ToFloat64(collector.groupPodsGauge.WithLabelValues("delta-group", "default", "Pending")))
+}
+
 func TestQuotaMetrics(t *testing.T) {
 	collector := NewCollector(slog.Default())
 
@@ -56,3 +79,284 @@ func TestSchedulerMetrics(t *testing.T) {
 
 	assert.NotNil(t, collector)
 }
+
+func TestSchedulingAttemptReasons(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	quota := collector.schedulingAttempts.WithLabelValues("failure", "insufficient-quota")
+	timeout := collector.schedulingAttempts.WithLabelValues("failure", "timeout")
+	success := collector.schedulingAttempts.WithLabelValues("success", "")
+	quotaBefore, timeoutBefore, successBefore := testutil.ToFloat64(quota), testutil.ToFloat64(timeout), testutil.ToFloat64(success)
+
+	collector.IncSchedulingAttemptsWithReason("failure", "insufficient-quota")
+	collector.IncSchedulingAttemptsWithReason("failure", "insufficient-quota")
+	collector.IncSchedulingAttemptsWithReason("failure", "timeout")
+	collector.IncSchedulingAttempts("success")
+
+	assert.Equal(t, quotaBefore+2, testutil.ToFloat64(quota))
+	assert.Equal(t, timeoutBefore+1, testutil.ToFloat64(timeout))
+	assert.Equal(t, successBefore+1, testutil.ToFloat64(success))
+}
+
+func TestWebhookMetrics(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	before := testutil.ToFloat64(collector.webhookParseErrors.WithLabelValues("/validate"))
+	collector.IncWebhookParseErrors("/validate")
+	collector.ObserveMutationPatchSize(512)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(collector.webhookParseErrors.WithLabelValues("/validate")))
+}
+
+func TestGather(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	before := testutil.ToFloat64(collector.schedulingAttempts.WithLabelValues("gather-test", ""))
+	collector.IncSchedulingAttempts("gather-test")
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	var found bool
+	for _, family := range families {
+		if family.GetName() != "volcano_scheduling_attempts_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == "gather-test" {
+					found = true
+					assert.Equal(t, before+1, metric.GetCounter().GetValue())
+				}
+			}
+		}
+	}
+	assert.True(t, found, "scheduling attempts family not gathered")
+}
+
+func TestGroupReadyDurationSummary(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	for i := 1; i <= 100; i++ {
+		collector.ObserveGroupReadyDuration(float64(i))
+	}
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	quantiles := make(map[float64]float64)
+	for _, family := range families {
+		if family.GetName() != "volcano_group_ready_duration_summary_seconds" {
+			continue
+		}
+		for _, q := range family.GetMetric()[0].GetSummary().GetQuantile() {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+	}
+
+	require.Len(t, quantiles, 3)
+	assert.InDelta(t, 50, quantiles[0.5], 5)
+	assert.InDelta(t, 90, quantiles[0.9], 2)
+	assert.InDelta(t, 99, quantiles[0.99], 1)
+}
+
+func TestEventDropRatio(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	// Drops before any delivery report 0 rather than dividing by zero.
+	collector.IncEventsDropped("RatioTest")
+	assert.Equal(t, 0.0, collector.EventDropRatio("RatioTest"))
+	assert.Equal(t, 0.0, testutil.ToFloat64(collector.eventsDropRatio.WithLabelValues("RatioTest")))
+
+	collector.AddEventDeliveries("RatioTest", 200)
+	collector.IncEventsDropped("RatioTest")
+
+	assert.Equal(t, 0.01, collector.EventDropRatio("RatioTest"))
+	assert.Equal(t, 0.01, testutil.ToFloat64(collector.eventsDropRatio.WithLabelValues("RatioTest")))
+	assert.Equal(t, 0.0, collector.EventDropRatio("NeverSeen"))
+}
+
+func TestSchedulingLatencyBuckets(t *testing.T) {
+	collector := NewCollector(slog.Default(),
+		WithSchedulingLatencyBuckets(prometheus.ExponentialBuckets(0.0001, 2, 16)))
+
+	collector.ObserveSchedulingLatency(0.0002)
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[float64]uint64)
+	for _, family := range families {
+		if family.GetName() != "volcano_scheduling_latency_seconds" {
+			continue
+		}
+		for _, bucket := range family.GetMetric()[0].GetHistogram().GetBucket() {
+			counts[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+		}
+	}
+
+	require.Len(t, counts, 16)
+	assert.Equal(t, uint64(0), counts[0.0001])
+	assert.Equal(t, uint64(1), counts[0.0002])
+
+	// Collectors without options still share the default metrics.
+	assert.NotSame(t, collector.metricSet, NewCollector(nil).metricSet)
+	assert.Same(t, NewCollector(nil).metricSet, NewCollector(nil).metricSet)
+}
+
+func TestDisabledMetrics(t *testing.T) {
+	collector := NewCollector(slog.Default(), WithDisabledMetrics("volcano_group_pods"))
+
+	collector.SetGroupPods("test-group", "default", "Running", 4)
+	collector.SetGroupsTotal("ready", 1)
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.False(t, names["volcano_group_pods"])
+	assert.True(t, names["volcano_groups_total"])
+	assert.Nil(t, collector.groupPodsGauge)
+}
+
+func TestQuotaPreemptionNamespaces(t *testing.T) {
+	collector := NewCollector(slog.Default())
+
+	flow := collector.quotaPreemptions.WithLabelValues("team-a", "team-b")
+	unlabeled := collector.quotaPreemptions.WithLabelValues("", "")
+	flowBefore, unlabeledBefore := testutil.ToFloat64(flow), testutil.ToFloat64(unlabeled)
+
+	collector.IncQuotaPreemptionsBetween("team-a", "team-b")
+	collector.IncQuotaPreemptionsBetween("team-a", "team-b")
+	collector.IncQuotaPreemptions()
+
+	assert.Equal(t, flowBefore+2, testutil.ToFloat64(flow))
+	assert.Equal(t, unlabeledBefore+1, testutil.ToFloat64(unlabeled))
+}
+
+func TestGroupReadyDurationByQueue(t *testing.T) {
+	collector := NewCollector(slog.Default(), WithDisabledMetrics())
+
+	collector.ObserveGroupReadyDurationByQueue("gpu", 30)
+	collector.ObserveGroupReadyDurationByQueue("gpu", 90)
+	collector.ObserveGroupReadyDurationByQueue("cpu", 5)
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+
+	counts := make(map[string]uint64)
+	sums := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "volcano_group_ready_duration_by_queue_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			queue := metric.GetLabel()[0].GetValue()
+			counts[queue] = metric.GetHistogram().GetSampleCount()
+			sums[queue] = metric.GetHistogram().GetSampleSum()
+		}
+	}
+
+	assert.Equal(t, map[string]uint64{"gpu": 2, "cpu": 1}, counts)
+	assert.Equal(t, map[string]float64{"gpu": 120, "cpu": 5}, sums)
+}
+
+func TestDebugHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+
+	// The metrics server does not expose pprof.
+	rec = httptest.NewRecorder()
+	NewCollector(slog.Default()).handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, "127.0.0.1:6060", DebugAddr(6060))
+}
+
+// recordingBackend is a Backend that records every call.
+type recordingBackend struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recordingBackend) record(call string, name string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key+"="+labels[key])
+	}
+	sort.Strings(keys)
+	r.calls = append(r.calls, fmt.Sprintf("%s %s{%s} %g", call, name, strings.Join(keys, ","), value))
+}
+
+func (r *recordingBackend) SetGauge(name string, labels Labels, value float64) {
+	r.record("set", name, labels, value)
+}
+
+func (r *recordingBackend) AddGauge(name string, labels Labels, delta float64) {
+	r.record("add", name, labels, delta)
+}
+
+func (r *recordingBackend) IncCounter(name string, labels Labels) {
+	r.record("inc", name, labels, 1)
+}
+
+func (r *recordingBackend) Observe(name string, labels Labels, value float64) {
+	r.record("observe", name, labels, value)
+}
+
+func (r *recordingBackend) DeleteSeries(name string, labels Labels) {
+	r.record("delete", name, labels, 0)
+}
+
+func TestWithBackend(t *testing.T) {
+	backend := &recordingBackend{}
+	collector := NewCollector(slog.Default(), WithBackend(backend))
+
+	collector.SetGroupsTotal("ready", 5)
+	collector.ObserveGroupReadyDurationByQueue("gpu", 30)
+	collector.IncQuotaPreemptionsBetween("team-a", "team-b")
+	collector.IncEventsDropped("GroupCreated")
+	collector.IncWebhookInflightRequests()
+	collector.DecWebhookInflightRequests()
+	collector.SetEstimatorGroupSamples("default", "train", 3)
+	collector.DeleteEstimatorGroupSamples("default", "train")
+
+	assert.Equal(t, []string{
+		"set volcano_groups_total{state=ready} 5",
+		"observe volcano_group_ready_duration_by_queue_seconds{queue=gpu} 30",
+		"inc volcano_quota_preemptions_total{from_namespace=team-a,to_namespace=team-b} 1",
+		"inc volcano_events_dropped_total{type=GroupCreated} 1",
+		"set volcano_events_drop_ratio{type=GroupCreated} 0",
+		"add volcano_webhook_inflight_requests{} 1",
+		"add volcano_webhook_inflight_requests{} -1",
+		"set volcano_estimator_group_samples{group=train,namespace=default} 3",
+		"delete volcano_estimator_group_samples{group=train,namespace=default} 0",
+	}, backend.calls)
+
+	// Nothing reaches Prometheus.
+	families, err := collector.Gather()
+	require.NoError(t, err)
+	assert.Empty(t, families)
+}
+
+func TestWebhookRequests(t *testing.T) {
+	collector := NewCollector(slog.Default(), WithDisabledMetrics())
+
+	collector.IncWebhookRequests("/validate", "CREATE", false)
+	collector.IncWebhookRequests("/validate", "CREATE", false)
+	collector.IncWebhookRequests("/validate", "UPDATE", true)
+	collector.IncWebhookRequests("/mutate", "CREATE", true)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.webhookRequests.WithLabelValues("/validate", "CREATE", "false")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.webhookRequests.WithLabelValues("/validate", "UPDATE", "true")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.webhookRequests.WithLabelValues("/mutate", "CREATE", "true")))
+	assert.Equal(t, 3, testutil.CollectAndCount(collector.webhookRequests))
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+)
+
+// DebugHandler returns a handler serving the net/http/pprof profiles under
+// /debug/pprof/. It must only be served on a private debug listener, never
+// alongside admission or metrics traffic.
+func DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// DebugAddr returns the loopback address the debug server listens on for
+// port, so profiles are only reachable from inside the pod.
+func DebugAddr(port int) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// ServeDebug serves DebugHandler over plain HTTP on the loopback interface.
+func (c *Collector) ServeDebug(port int) error {
+	addr := DebugAddr(port)
+	c.logger.Info("starting debug server", "addr", addr)
+	return http.ListenAndServe(addr, DebugHandler())
+}
@@ -0,0 +1,2 @@
+// Package metrics exposes Prometheus instrumentation for volcano components.
+package metrics
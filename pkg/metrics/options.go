@@ -0,0 +1,58 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// DefaultSchedulingLatencyBuckets are the volcano_scheduling_latency_seconds
+// buckets used unless WithSchedulingLatencyBuckets says otherwise: 1ms
+// doubling up to about 2s.
+var DefaultSchedulingLatencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 12)
+
+// Option configures a Collector.
+type Option func(*collectorConfig)
+
+type collectorConfig struct {
+	schedulingLatencyBuckets []float64
+	disabled                 map[string]bool
+	backend                  Backend
+}
+
+func defaultCollectorConfig() collectorConfig {
+	return collectorConfig{
+		schedulingLatencyBuckets: DefaultSchedulingLatencyBuckets,
+	}
+}
+
+// WithSchedulingLatencyBuckets sets the upper bounds, in seconds, of the
+// volcano_scheduling_latency_seconds buckets. Deployments with fast in-memory
+// scheduling can start them well below 1ms, e.g.
+// prometheus.ExponentialBuckets(0.0001, 2, 16). Empty buckets are ignored.
+func WithSchedulingLatencyBuckets(buckets []float64) Option {
+	return func(cfg *collectorConfig) {
+		if len(buckets) > 0 {
+			cfg.schedulingLatencyBuckets = buckets
+		}
+	}
+}
+
+// WithDisabledMetrics leaves the metrics with the given names, such as
+// "volcano_group_pods", unregistered so they are never served; updating them
+// does nothing. Unknown names are ignored.
+func WithDisabledMetrics(names ...string) Option {
+	return func(cfg *collectorConfig) {
+		if cfg.disabled == nil {
+			cfg.disabled = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			cfg.disabled[name] = true
+		}
+	}
+}
+
+// WithBackend sends every metric update to backend instead of Prometheus, for
+// binaries that already export metrics another way. The collector's registry
+// is then left empty, so Gather and ServeMetrics report nothing.
+func WithBackend(backend Backend) Option {
+	return func(cfg *collectorConfig) {
+		cfg.backend = backend
+	}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// defaultHandshakeFailureThreshold is how many TLS handshakes may fail without
+// an admission review in between before the replica reports itself unready.
+const defaultHandshakeFailureThreshold = 5
+
+// handshakeBreaker opens once threshold TLS handshakes have failed with no
+// admission review served in between, typically because the served
+// certificate is no longer trusted, and closes again on the next admission
+// review. Only admission reviews count as successes: the kubelet's probes skip
+// certificate verification, so their handshakes succeed even while the API
+// server's fail. While open, /readyz fails so the API server stops routing
+// admission traffic to this replica.
+type handshakeBreaker struct {
+	threshold int
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+}
+
+// newHandshakeBreaker returns a breaker opening after threshold consecutive
+// failures, or nil when threshold is zero or less.
+func newHandshakeBreaker(threshold int, logger *slog.Logger) *handshakeBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &handshakeBreaker{threshold: threshold, logger: logger}
+}
+
+// failure records a failed handshake.
+func (b *handshakeBreaker) failure(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if !b.open && b.failures >= b.threshold {
+		b.open = true
+		b.logger.Error("TLS handshakes failing, marking replica unready",
+			"consecutiveFailures", b.failures, "lastError", reason)
+	}
+}
+
+// success records an admission review received over a completed handshake.
+func (b *handshakeBreaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.open {
+		b.logger.Info("admission review received, marking replica ready",
+			"consecutiveFailures", b.failures)
+	}
+	b.failures = 0
+	b.open = false
+}
+
+// isOpen reports whether the breaker is open. A nil breaker is always closed.
+func (b *handshakeBreaker) isOpen() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// httpErrorLog returns a logger for http.Server.ErrorLog that forwards
+// server errors to logger and reports TLS handshake errors to b. Clients
+// hanging up mid-handshake, such as TCP probes, are not counted.
+func httpErrorLog(logger *slog.Logger, b *handshakeBreaker) *log.Logger {
+	return log.New(&httpErrorWriter{logger: logger, breaker: b}, "", 0)
+}
+
+type httpErrorWriter struct {
+	logger  *slog.Logger
+	breaker *handshakeBreaker
+}
+
+func (w *httpErrorWriter) Write(p []byte) (int, error) {
+	message := string(bytes.TrimSpace(p))
+	w.logger.Warn("http server error", "message", message)
+
+	if w.breaker != nil && strings.Contains(message, "TLS handshake error") && !strings.HasSuffix(message, "EOF") {
+		w.breaker.failure(message)
+	}
+	return len(p), nil
+}
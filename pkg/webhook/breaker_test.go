@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// breakerTestServer serves /health and /validate over TLS with a
+// self-signed certificate, failing readiness after two failed handshakes.
+func breakerTestServer(t *testing.T) (server *Server, baseURL string) {
+	t.Helper()
+	certFile, keyFile := writeKeyPair(t, t.TempDir(), "webhook")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	server = NewServerWithOptions(
+		WithLogger(logger),
+		WithTLS(certFile, keyFile),
+		WithHandshakeFailureThreshold(2),
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", server.handleHealth)
+	mux.HandleFunc("/validate", server.handleValidate)
+	httpServer := server.newHTTPServer(mux, newCertReloader(certFile, keyFile, logger, nil).GetCertificate)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = httpServer.ServeTLS(listener, "", "") }()
+	t.Cleanup(func() { _ = httpServer.Close() })
+
+	return server, "https://" + listener.Addr().String()
+}
+
+func breakerReady(server *Server) int {
+	rec := httptest.NewRecorder()
+	server.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return rec.Code
+}
+
+// The self-signed certificate is untrusted, so the untrusting client's
+// handshakes fail while the trusting client's, like the kubelet's probes,
+// succeed.
+var (
+	untrusting = &http.Client{Timeout: 5 * time.Second}
+	trusting   = &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+)
+
+func TestHandshakeBreaker_FlipsReadiness(t *testing.T) {
+	server, baseURL := breakerTestServer(t)
+
+	for range 2 {
+		_, err := untrusting.Get(baseURL + "/health")
+		require.Error(t, err)
+	}
+	assert.Eventually(t, func() bool { return breakerReady(server) == http.StatusServiceUnavailable },
+		5*time.Second, 10*time.Millisecond)
+
+	// A probe does not close the breaker; an admission review does.
+	resp, err := trusting.Get(baseURL + "/health")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, breakerReady(server))
+
+	object := []byte(`{"metadata":{"name":"group"},"spec":{"minMember":2,"scheduleTimeoutSeconds":600}}`)
+	resp, err = trusting.Post(baseURL+"/validate", "application/json",
+		bytes.NewReader(captureReview(t, "breaker", object)))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, http.StatusOK, breakerReady(server))
+}
+
+func TestHandshakeBreaker_ProbesDoNotReset(t *testing.T) {
+	server, baseURL := breakerTestServer(t)
+
+	// Probes between the failed handshakes do not reset the count.
+	for range 2 {
+		_, err := untrusting.Get(baseURL + "/health")
+		require.Error(t, err)
+
+		resp, err := trusting.Get(baseURL + "/health")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.Eventually(t, func() bool { return breakerReady(server) == http.StatusServiceUnavailable },
+		5*time.Second, 10*time.Millisecond)
+}
+
+func TestHandshakeBreaker_Disabled(t *testing.T) {
+	server := NewServerWithOptions(WithHandshakeFailureThreshold(0))
+	assert.Nil(t, server.breaker)
+
+	rec := httptest.NewRecorder()
+	server.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// responseCache remembers recent validation responses by request UID so that
+// API server retries of the same request get the same decision without
+// recomputing it. The oldest entry is evicted once size is reached.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]cachedResponse
+	order   []string // keys in insertion order
+}
+
+type cachedResponse struct {
+	response *admissionv1.AdmissionResponse
+	expires  time.Time
+}
+
+// newResponseCache returns a cache of size entries kept for ttl, or nil,
+// which caches nothing, when either is not positive.
+func newResponseCache(size int, ttl time.Duration) *responseCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+
+	return &responseCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]cachedResponse, size),
+	}
+}
+
+// get returns a copy of the response cached under key if it has not expired
+// by now.
+func (c *responseCache) get(key string, now time.Time) (*admissionv1.AdmissionResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	return entry.response.DeepCopy(), true
+}
+
+// put caches a copy of response under key until now plus the TTL.
+func (c *responseCache) put(key string, response *admissionv1.AdmissionResponse, now time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		c.evict(now)
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = cachedResponse{
+		response: response.DeepCopy(),
+		expires:  now.Add(c.ttl),
+	}
+}
+
+// evict drops expired entries from the front of the queue, then the oldest
+// entries until there is room for one more. The caller must hold c.mu.
+func (c *responseCache) evict(now time.Time) {
+	for len(c.order) > 0 {
+		oldest := c.order[0]
+		if len(c.order) < c.size && now.Before(c.entries[oldest].expires) {
+			return
+		}
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+}
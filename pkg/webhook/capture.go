@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// defaultCaptureMaxBytes caps a captured request when WithRequestCapture is
+// given no limit.
+const defaultCaptureMaxBytes = 64 << 10
+
+// CapturedRequest is an admission request as the webhook received it, served
+// by /debug/requests.
+type CapturedRequest struct {
+	Time time.Time `json:"time"`
+	Path string    `json:"path"`
+
+	// Request is the AdmissionRequest JSON. When it exceeds the capture
+	// size cap, its object and oldObject are dropped and Truncated is set.
+	Request   json.RawMessage `json:"request"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// requestCapture keeps the last size admission requests in a ring buffer so
+// a disputed decision can be inspected. It holds object contents, so it is
+// only created when capture is enabled.
+type requestCapture struct {
+	mu       sync.Mutex
+	maxBytes int
+	entries  []CapturedRequest
+	next     int // slot the next request is written to
+	full     bool
+}
+
+// newRequestCapture returns a capture of the last size requests of at most
+// maxBytes each, or nil, which captures nothing, when size is not positive.
+func newRequestCapture(size, maxBytes int) *requestCapture {
+	if size <= 0 {
+		return nil
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultCaptureMaxBytes
+	}
+
+	return &requestCapture{
+		maxBytes: maxBytes,
+		entries:  make([]CapturedRequest, size),
+	}
+}
+
+// add records req, received on path at now, overwriting the oldest capture
+// once the buffer is full.
+func (c *requestCapture) add(path string, req *admissionv1.AdmissionRequest, now time.Time) error {
+	if c == nil || req == nil {
+		return nil
+	}
+
+	captured := CapturedRequest{Time: now, Path: path}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if len(raw) > c.maxBytes {
+		stripped := req.DeepCopy()
+		stripped.Object.Raw = nil
+		stripped.OldObject.Raw = nil
+		if raw, err = json.Marshal(stripped); err != nil {
+			return err
+		}
+		captured.Truncated = true
+	}
+	captured.Request = raw
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[c.next] = captured
+	c.next = (c.next + 1) % len(c.entries)
+	if c.next == 0 {
+		c.full = true
+	}
+	return nil
+}
+
+// list returns the captured requests, oldest first.
+func (c *requestCapture) list() []CapturedRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.full {
+		return append([]CapturedRequest(nil), c.entries[:c.next]...)
+	}
+	captured := make([]CapturedRequest, 0, len(c.entries))
+	captured = append(captured, c.entries[c.next:]...)
+	return append(captured, c.entries[:c.next]...)
+}
+
+// captureRequest records req for /debug/requests when capture is enabled.
+func (s *Server) captureRequest(r *http.Request, req *admissionv1.AdmissionRequest) {
+	if err := s.captured.add(r.URL.Path, req, s.now()); err != nil {
+		s.logger.Error("failed to capture request", "error", err)
+	}
+}
+
+// handleCapturedRequests serves the captured requests as a JSON array,
+// oldest first.
+func (s *Server) handleCapturedRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.captured.list()); err != nil {
+		s.logger.Error("failed to encode captured requests", "error", err)
+	}
+}
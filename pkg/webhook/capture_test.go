@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func captureReview(t *testing.T, uid string, object []byte) []byte {
+	t.Helper()
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID(uid),
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: object},
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func capturedRequests(t *testing.T, handler http.Handler) []CapturedRequest {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/requests", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var captured []CapturedRequest
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &captured))
+	return captured
+}
+
+func TestRequestCapture(t *testing.T) {
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithRequestCapture(2, 1024),
+	)
+	handler := server.routes()
+	debug := server.debugRoutes()
+	object := []byte(`{"metadata":{"name":"group"},"spec":{"minMember":2,"scheduleTimeoutSeconds":600}}`)
+
+	for i, path := range []string{"/validate", "/mutate", "/validate"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newJSONRequest(path, captureReview(t, fmt.Sprintf("uid-%d", i), object)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Only the last two are kept, oldest first.
+	captured := capturedRequests(t, debug)
+	require.Len(t, captured, 2)
+	assert.Equal(t, "/mutate", captured[0].Path)
+	assert.Equal(t, "/validate", captured[1].Path)
+	assert.False(t, captured[1].Truncated)
+
+	var req admissionv1.AdmissionRequest
+	require.NoError(t, json.Unmarshal(captured[1].Request, &req))
+	assert.Equal(t, types.UID("uid-2"), req.UID)
+	assert.JSONEq(t, string(object), string(req.Object.Raw))
+
+	// An oversized request is kept without its object.
+	large := []byte(`{"metadata":{"name":"group","annotations":{"note":"` + strings.Repeat("x", 2048) + `"}},"spec":{"minMember":2,"scheduleTimeoutSeconds":600}}`)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newJSONRequest("/validate", captureReview(t, "uid-large", large)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	captured = capturedRequests(t, debug)
+	require.Len(t, captured, 2)
+	assert.True(t, captured[1].Truncated)
+	var truncated admissionv1.AdmissionRequest
+	require.NoError(t, json.Unmarshal(captured[1].Request, &truncated))
+	assert.Equal(t, types.UID("uid-large"), truncated.UID)
+	assert.Empty(t, truncated.Object.Raw)
+
+	// Captures are never served on the admission port.
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/requests", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRequestCapture_DisabledByDefault(t *testing.T) {
+	server := NewServer(8443, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	server.debugRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/requests", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// pprof is still served.
+	rec = httptest.NewRecorder()
+	server.debugRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
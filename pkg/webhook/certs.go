@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// certReloader serves the TLS keypair from disk and re-reads it whenever
+// either file changes, so certificates rotated by cert-manager are picked up
+// without a restart.
+type certReloader struct {
+	certFile  string
+	keyFile   string
+	logger    *slog.Logger
+	collector *metrics.Collector
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger *slog.Logger, collector *metrics.Collector) *certReloader {
+	return &certReloader{
+		certFile:  certFile,
+		keyFile:   keyFile,
+		logger:    logger,
+		collector: collector,
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate. When a reload fails
+// the previous certificate keeps being served; a changed file is only
+// retried once it changes again.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certMod, keyMod, err := r.modTimes()
+	if err == nil && r.cert != nil && certMod.Equal(r.certMod) && keyMod.Equal(r.keyMod) {
+		return r.cert, nil
+	}
+	if err == nil {
+		r.certMod, r.keyMod = certMod, keyMod
+		err = r.load()
+	}
+
+	if err != nil {
+		if r.collector != nil {
+			r.collector.IncWebhookCertReloadFailures()
+		}
+		r.logger.Error("failed to reload TLS certificate", "error", err)
+		if r.cert == nil {
+			return nil, err
+		}
+	}
+	return r.cert, nil
+}
+
+// modTimes returns the modification times of the cert and key files.
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, certErr := os.Stat(r.certFile)
+	keyInfo, keyErr := os.Stat(r.keyFile)
+	if err := errors.Join(certErr, keyErr); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid TLS keypair: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// load reads the keypair from disk. The caller must hold r.mu.
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid TLS keypair: %w", err)
+	}
+
+	r.cert = &cert
+	if r.collector != nil {
+		r.collector.IncWebhookCertReloads()
+	}
+	r.logger.Info("loaded TLS certificate", "file", r.certFile)
+	return nil
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// writeKeyPair writes a self-signed certificate for commonName and its key
+// to PEM files in dir and returns their paths.
+func writeKeyPair(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+// counterValue returns the value of the unlabeled counter name gathered from
+// collector.
+func counterValue(t *testing.T, collector *metrics.Collector, name string) float64 {
+	t.Helper()
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first")
+	collector := metrics.NewCollector(slog.Default())
+	reloader := newCertReloader(certFile, keyFile, slog.New(slog.NewTextHandler(io.Discard, nil)), collector)
+
+	reloadsBefore := counterValue(t, collector, "volcano_webhook_cert_reloads_total")
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", cert.Leaf.Subject.CommonName)
+
+	// Unchanged files are served from memory.
+	_, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, reloadsBefore+1, counterValue(t, collector, "volcano_webhook_cert_reloads_total"))
+
+	writeKeyPair(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	cert, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", cert.Leaf.Subject.CommonName)
+	assert.Equal(t, reloadsBefore+2, counterValue(t, collector, "volcano_webhook_cert_reloads_total"))
+}
+
+func TestCertReloader_CountsFailures(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "valid")
+	collector := metrics.NewCollector(slog.Default())
+	reloader := newCertReloader(certFile, keyFile, slog.New(slog.NewTextHandler(io.Discard, nil)), collector)
+
+	_, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	failuresBefore := counterValue(t, collector, "volcano_webhook_cert_reload_failures_total")
+
+	// A corrupt rotation keeps serving the previous certificate.
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "valid", cert.Leaf.Subject.CommonName)
+	assert.Equal(t, failuresBefore+1, counterValue(t, collector, "volcano_webhook_cert_reload_failures_total"))
+
+	// Without a previous certificate the failure is returned.
+	empty := newCertReloader(filepath.Join(dir, "missing.crt"), keyFile, slog.New(slog.NewTextHandler(io.Discard, nil)), collector)
+	_, err = empty.GetCertificate(nil)
+	assert.Error(t, err)
+	assert.Equal(t, failuresBefore+2, counterValue(t, collector, "volcano_webhook_cert_reload_failures_total"))
+}
+
+func TestRun_RejectsMismatchedKeyPair(t *testing.T) {
+	certFile, _ := writeKeyPair(t, t.TempDir(), "cert")
+	_, keyFile := writeKeyPair(t, t.TempDir(), "other")
+
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithPort(0),
+		WithTLS(certFile, keyFile),
+	)
+
+	err := server.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid TLS keypair: ")
+	assert.Contains(t, err.Error(), "private key does not match public key")
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds the admission policy applied by the server. It can be loaded
+// from a YAML or JSON file with LoadConfig.
+type Config struct {
+	Defaults   MutationDefaults `json:"defaults"`
+	Namespaces NamespaceFilter  `json:"namespaces"`
+
+	// FailOpen admits objects, with a warning, when the webhook itself fails,
+	// e.g. cannot reach the queue lister; otherwise they are denied. Set it
+	// to match the webhook configuration's failurePolicy: true for Ignore,
+	// false for Fail.
+	FailOpen bool `json:"failOpen,omitempty"`
+
+	// PatchTestGuards prefixes JSON patch operations with "test" operations
+	// asserting the values they overwrite, so the API server rejects the
+	// patch if the object changed in the meantime.
+	PatchTestGuards bool `json:"patchTestGuards,omitempty"`
+
+	// MinPriority and MaxPriority bound a user-supplied priority.
+	MinPriority int `json:"minPriority"`
+	MaxPriority int `json:"maxPriority"`
+
+	// ClusterMaxMinMember caps minMember for every JobGroup, whatever its
+	// queue. Zero disables the cap.
+	ClusterMaxMinMember int `json:"clusterMaxMinMember,omitempty"`
+
+	// MinScheduleTimeoutSeconds and MaxScheduleTimeoutSeconds bound a
+	// user-supplied scheduleTimeoutSeconds, so groups cannot time out before
+	// the scheduler gets to them. A zero maximum leaves it unbounded.
+	MinScheduleTimeoutSeconds int `json:"minScheduleTimeoutSeconds"`
+	MaxScheduleTimeoutSeconds int `json:"maxScheduleTimeoutSeconds,omitempty"`
+
+	// Schema, when set, validates the whole object in place of the built-in
+	// spec checks.
+	Schema *Schema `json:"schema,omitempty"`
+
+	// MaxTasks caps the number of entries in spec.tasks, so a generated
+	// JobGroup cannot swamp the scheduler. Zero disables the cap.
+	MaxTasks int `json:"maxTasks,omitempty"`
+
+	// MaxContainerRequests caps what any single task container may request.
+	// Resources not listed are unbounded; an empty list disables the check.
+	MaxContainerRequests corev1.ResourceList `json:"maxContainerRequests,omitempty"`
+
+	// StrictTaskReplicas requires the tasks' replicas to add up to
+	// maxMember when both are set, catching a forgotten task. Leave it off
+	// where maxMember is only an upper bound.
+	StrictTaskReplicas bool `json:"strictTaskReplicas,omitempty"`
+
+	// AllowedResources lists the resources task containers may request,
+	// e.g. cpu, memory and nvidia.com/gpu. An empty list allows any resource.
+	AllowedResources []corev1.ResourceName `json:"allowedResources,omitempty"`
+
+	// RequiredLabels lists label keys every JobGroup must carry, e.g. team
+	// and cost-center for cost attribution. Empty enforces none.
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+
+	// ImmutableAnnotations lists annotation keys that an UPDATE may not
+	// change or remove once set, e.g. an owner stamped at creation.
+	ImmutableAnnotations []string `json:"immutableAnnotations,omitempty"`
+
+	// Inject is metadata the mutator adds to every JobGroup it admits.
+	Inject MetadataInjection `json:"inject,omitempty"`
+}
+
+// MetadataInjection lists labels and annotations the mutator sets on admitted
+// JobGroups, overriding any existing values for the same keys.
+type MetadataInjection struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// DefaultsAnnotation, when set, is an annotation recording the spec
+	// fields the mutator defaulted, e.g. "maxMember,priority".
+	DefaultsAnnotation string `json:"defaultsAnnotation,omitempty"`
+}
+
+// MutationDefaults are the values applied by the mutating webhook when a
+// JobGroup leaves the corresponding field unset.
+type MutationDefaults struct {
+	// MaxMemberFactor sets maxMember to minMember * MaxMemberFactor.
+	MaxMemberFactor        int `json:"maxMemberFactor"`
+	Priority               int `json:"priority"`
+	ScheduleTimeoutSeconds int `json:"scheduleTimeoutSeconds"`
+}
+
+// NamespaceFilter selects the namespaces the webhook enforces policy in.
+// Requests from other namespaces are admitted unchanged. Entries are exact
+// names or path.Match patterns, e.g. "team-*".
+type NamespaceFilter struct {
+	// Include, when non-empty, restricts enforcement to these namespaces.
+	Include []string `json:"include,omitempty"`
+	// Exclude lists namespaces that are never enforced.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// DefaultConfig returns the built-in admission policy.
+func DefaultConfig() Config {
+	return Config{
+		Defaults: MutationDefaults{
+			MaxMemberFactor:        2,
+			Priority:               50,
+			ScheduleTimeoutSeconds: 600,
+		},
+		MinPriority:               0,
+		MaxPriority:               1000,
+		MinScheduleTimeoutSeconds: 30,
+	}
+}
+
+// LoadConfig reads a YAML or JSON config file. Fields the file leaves unset
+// keep their DefaultConfig values.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config for values the server cannot apply.
+func (c Config) Validate() error {
+	if c.MinPriority > c.MaxPriority {
+		return fmt.Errorf("minPriority %d is above maxPriority %d", c.MinPriority, c.MaxPriority)
+	}
+
+	if p := c.Defaults.Priority; p < c.MinPriority || p > c.MaxPriority {
+		return fmt.Errorf("defaults.priority %d is outside the allowed range %d to %d", p, c.MinPriority, c.MaxPriority)
+	}
+
+	if c.Defaults.MaxMemberFactor < 1 {
+		return fmt.Errorf("defaults.maxMemberFactor must be at least 1")
+	}
+
+	if c.Defaults.ScheduleTimeoutSeconds <= 0 {
+		return fmt.Errorf("defaults.scheduleTimeoutSeconds must be positive")
+	}
+
+	if c.ClusterMaxMinMember < 0 {
+		return fmt.Errorf("clusterMaxMinMember must not be negative")
+	}
+
+	if c.MaxTasks < 0 {
+		return fmt.Errorf("maxTasks must not be negative")
+	}
+
+	if c.MinScheduleTimeoutSeconds < 0 {
+		return fmt.Errorf("minScheduleTimeoutSeconds must not be negative")
+	}
+
+	if c.MaxScheduleTimeoutSeconds > 0 && c.MinScheduleTimeoutSeconds > c.MaxScheduleTimeoutSeconds {
+		return fmt.Errorf("minScheduleTimeoutSeconds %d is above maxScheduleTimeoutSeconds %d",
+			c.MinScheduleTimeoutSeconds, c.MaxScheduleTimeoutSeconds)
+	}
+
+	if timeout := c.Defaults.ScheduleTimeoutSeconds; timeout < c.MinScheduleTimeoutSeconds ||
+		(c.MaxScheduleTimeoutSeconds > 0 && timeout > c.MaxScheduleTimeoutSeconds) {
+		return fmt.Errorf("defaults.scheduleTimeoutSeconds %d is outside the allowed range", timeout)
+	}
+
+	if err := c.Namespaces.Validate(); err != nil {
+		return fmt.Errorf("namespaces: %w", err)
+	}
+
+	return nil
+}
+
+// Matches reports whether policy should be enforced in namespace.
+func (f NamespaceFilter) Matches(namespace string) bool {
+	if matchesAny(f.Exclude, namespace) {
+		return false
+	}
+	return len(f.Include) == 0 || matchesAny(f.Include, namespace)
+}
+
+// Validate checks that every entry is a well-formed pattern.
+func (f NamespaceFilter) Validate() error {
+	for _, pattern := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether namespace equals or matches any of patterns.
+// Malformed patterns, rejected by Validate, only match exactly.
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if pattern == namespace {
+			return true
+		}
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+defaults:
+  maxMemberFactor: 3
+  priority: 10
+  scheduleTimeoutSeconds: 120
+namespaces:
+  exclude: [kube-system]
+maxPriority: 500
+`)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, MutationDefaults{MaxMemberFactor: 3, Priority: 10, ScheduleTimeoutSeconds: 120}, cfg.Defaults)
+	assert.Equal(t, []string{"kube-system"}, cfg.Namespaces.Exclude)
+	assert.Equal(t, 0, cfg.MinPriority) // unset fields keep their defaults
+	assert.Equal(t, 500, cfg.MaxPriority)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{name: "unknown field", contents: "maxPriorty: 10\n", err: "unknown field"},
+		{name: "inverted priority range", contents: "minPriority: 10\nmaxPriority: 5\n", err: "minPriority 10 is above maxPriority 5"},
+		{name: "max member factor", contents: "defaults:\n  maxMemberFactor: 0\n", err: "maxMemberFactor"},
+		{name: "inverted timeout range", contents: "minScheduleTimeoutSeconds: 60\nmaxScheduleTimeoutSeconds: 30\n", err: "minScheduleTimeoutSeconds 60 is above maxScheduleTimeoutSeconds 30"},
+		{name: "negative member cap", contents: "clusterMaxMinMember: -1\n", err: "clusterMaxMinMember must not be negative"},
+		{name: "negative task cap", contents: "maxTasks: -1\n", err: "maxTasks must not be negative"},
+		{name: "default priority above range", contents: "maxPriority: 10\n", err: "defaults.priority 50 is outside the allowed range 0 to 10"},
+		{name: "default priority below range", contents: "minPriority: 60\n", err: "defaults.priority 50 is outside the allowed range 60 to 1000"},
+		{name: "bad namespace pattern", contents: "namespaces:\n  exclude: [\"team-[\"]\n", err: `invalid namespace pattern "team-["`},
+		{name: "default timeout below floor", contents: "minScheduleTimeoutSeconds: 900\n", err: "defaults.scheduleTimeoutSeconds 600 is outside the allowed range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.contents))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestDefaultConfig_Valid(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+}
+
+func TestNamespaceFilter_Patterns(t *testing.T) {
+	filter := NamespaceFilter{Exclude: []string{"team-*", "kube-system"}}
+	assert.False(t, filter.Matches("team-a"))
+	assert.False(t, filter.Matches("team-b"))
+	assert.False(t, filter.Matches("kube-system"))
+	assert.True(t, filter.Matches("prod"))
+	assert.True(t, filter.Matches("team"))
+
+	filter = NamespaceFilter{Include: []string{"prod-?", "staging"}, Exclude: []string{"prod-9"}}
+	assert.True(t, filter.Matches("prod-1"))
+	assert.True(t, filter.Matches("staging"))
+	assert.False(t, filter.Matches("prod-9"))
+	assert.False(t, filter.Matches("prod-10"))
+	assert.False(t, filter.Matches("dev"))
+
+	require.NoError(t, filter.Validate())
+	assert.Error(t, NamespaceFilter{Include: []string{"["}}.Validate())
+}
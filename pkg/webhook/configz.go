@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// redacted replaces values /configz does not reveal.
+const redacted = "REDACTED"
+
+// Configz is the /configz response body: the effective admission policy and
+// the files it and the serving certificate were loaded from.
+type Configz struct {
+	Config     Config `json:"config"`
+	ConfigFile string `json:"configFile,omitempty"`
+
+	// CertFile and KeyFile are redacted when set, so the response does not
+	// reveal where key material lives.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+}
+
+// configz returns the running configuration, including any /reload.
+func (s *Server) configz() Configz {
+	configz := Configz{
+		Config:     s.currentConfig(),
+		ConfigFile: s.configFile,
+	}
+	if s.certFile != "" {
+		configz.CertFile = redacted
+	}
+	if s.keyFile != "" {
+		configz.KeyFile = redacted
+	}
+	return configz
+}
+
+// handleConfigz serves the effective configuration as JSON, mirroring the
+// kubelet's /configz. It is read-only.
+func (s *Server) handleConfigz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.configz()); err != nil {
+		s.logger.Error("failed to encode config", "error", err)
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleConfigz(t *testing.T) {
+	path := writeConfig(t, "defaults:\n  maxMemberFactor: 2\n  priority: 50\n  scheduleTimeoutSeconds: 600\nnamespaces:\n  exclude: [kube-system]\n")
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithTLS("/etc/webhook/tls.crt", "/etc/webhook/tls.key"),
+		WithConfig(cfg),
+		WithConfigReload(path, "secret"),
+	)
+
+	configz := func() (Configz, string) {
+		rec := httptest.NewRecorder()
+		server.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/configz", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var body Configz
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body, rec.Body.String()
+	}
+
+	body, raw := configz()
+	assert.Equal(t, 50, body.Config.Defaults.Priority)
+	assert.Equal(t, 1000, body.Config.MaxPriority)
+	assert.Equal(t, []string{"kube-system"}, body.Config.Namespaces.Exclude)
+	assert.Equal(t, path, body.ConfigFile)
+	assert.Equal(t, "REDACTED", body.CertFile)
+	assert.Equal(t, "REDACTED", body.KeyFile)
+	assert.NotContains(t, raw, "/etc/webhook")
+	assert.NotContains(t, raw, "secret")
+
+	// A reload is reflected.
+	require.NoError(t, os.WriteFile(path, []byte("defaults:\n  maxMemberFactor: 4\n  priority: 70\n  scheduleTimeoutSeconds: 300\n"), 0o600))
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	server.handleReload(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, _ = configz()
+	assert.Equal(t, 70, body.Config.Defaults.Priority)
+	assert.Empty(t, body.Config.Namespaces.Exclude)
+
+	rec = httptest.NewRecorder()
+	server.handleConfigz(rec, httptest.NewRequest(http.MethodPost, "/configz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
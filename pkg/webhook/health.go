@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Health statuses, from best to worst.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailing  = "failing"
+)
+
+// healthRank orders statuses so the overall status is the worst check's.
+var healthRank = map[string]int{HealthOK: 0, HealthDegraded: 1, HealthFailing: 2}
+
+// HealthChecker is implemented by dependencies, such as a QueueChecker, that
+// can report whether they are reachable.
+type HealthChecker interface {
+	Healthy() error
+}
+
+// CheckStatus is the result of one subcomponent check.
+type CheckStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthSummary is the /healthz/verbose response body.
+type HealthSummary struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks"`
+}
+
+// healthSummary runs every subcomponent check.
+func (s *Server) healthSummary() HealthSummary {
+	summary := HealthSummary{
+		Status: HealthOK,
+		Checks: map[string]CheckStatus{
+			"tls":          s.tlsHealth(),
+			"queueChecker": s.queueCheckerHealth(),
+			"metrics":      s.metricsHealth(),
+		},
+	}
+	for _, check := range summary.Checks {
+		if healthRank[check.Status] > healthRank[summary.Status] {
+			summary.Status = check.Status
+		}
+	}
+	return summary
+}
+
+// tlsHealth fails once the cert files have been unreadable past the grace
+// period, and is degraded within it or while handshakes keep failing.
+func (s *Server) tlsHealth() CheckStatus {
+	if err := s.checkCertFiles(); err != nil {
+		return CheckStatus{Status: HealthFailing, Message: err.Error()}
+	}
+
+	s.certMu.Lock()
+	unreadable := !s.certUnreadableSince.IsZero()
+	s.certMu.Unlock()
+	if unreadable {
+		return CheckStatus{Status: HealthDegraded, Message: "TLS cert files unreadable, within grace period"}
+	}
+	if s.breaker.isOpen() {
+		return CheckStatus{Status: HealthDegraded, Message: "TLS handshakes failing"}
+	}
+	return CheckStatus{Status: HealthOK}
+}
+
+// queueCheckerHealth is degraded when the queue checker reports itself
+// unreachable. Checkers that do not implement HealthChecker count as ok.
+func (s *Server) queueCheckerHealth() CheckStatus {
+	if s.queues == nil {
+		return CheckStatus{Status: HealthOK, Message: "not configured"}
+	}
+	if checker, ok := s.queues.(HealthChecker); ok {
+		if err := checker.Healthy(); err != nil {
+			return CheckStatus{Status: HealthDegraded, Message: "unreachable: " + err.Error()}
+		}
+	}
+	return CheckStatus{Status: HealthOK}
+}
+
+// metricsHealth is degraded when the collector cannot gather its metrics.
+func (s *Server) metricsHealth() CheckStatus {
+	if s.collector == nil {
+		return CheckStatus{Status: HealthOK, Message: "not configured"}
+	}
+	if _, err := s.collector.Gather(); err != nil {
+		return CheckStatus{Status: HealthDegraded, Message: err.Error()}
+	}
+	return CheckStatus{Status: HealthOK}
+}
+
+// handleHealthVerbose serves the health summary as JSON, with 503 Service
+// Unavailable when any check is failing.
+func (s *Server) handleHealthVerbose(w http.ResponseWriter, r *http.Request) {
+	summary := s.healthSummary()
+
+	code := http.StatusOK
+	if summary.Status == HealthFailing {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(summary); err != nil {
+		s.logger.Error("failed to encode health summary", "error", err)
+	}
+}
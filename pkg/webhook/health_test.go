@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// pingQueues is a fakeQueues that also reports its reachability.
+type pingQueues struct {
+	fakeQueues
+	healthErr error
+}
+
+func (p *pingQueues) Healthy() error {
+	return p.healthErr
+}
+
+func verboseHealth(t *testing.T, server *Server) (int, HealthSummary) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/verbose", nil))
+
+	var summary HealthSummary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	return rec.Code, summary
+}
+
+func TestHandleHealthVerbose(t *testing.T) {
+	queues := &pingQueues{}
+	server := NewServerWithOptions(
+		WithQueueChecker(queues),
+		WithCollector(metrics.NewCollector(slog.Default())),
+	)
+
+	code, summary := verboseHealth(t, server)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthOK, summary.Status)
+	assert.Equal(t, HealthOK, summary.Checks["tls"].Status)
+	assert.Equal(t, HealthOK, summary.Checks["queueChecker"].Status)
+	assert.Equal(t, HealthOK, summary.Checks["metrics"].Status)
+
+	queues.healthErr = errors.New("connection refused")
+	code, summary = verboseHealth(t, server)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthDegraded, summary.Status)
+	assert.Equal(t, CheckStatus{Status: HealthDegraded, Message: "unreachable: connection refused"},
+		summary.Checks["queueChecker"])
+	assert.Equal(t, HealthOK, summary.Checks["tls"].Status)
+}
+
+func TestHandleHealthVerbose_CertUnreadable(t *testing.T) {
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithTLS("/nonexistent/tls.crt", "/nonexistent/tls.key"),
+		WithCertGracePeriod(time.Minute),
+	)
+	now := time.Now()
+	server.now = func() time.Time { return now }
+
+	code, summary := verboseHealth(t, server)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthDegraded, summary.Status)
+	assert.Equal(t, HealthDegraded, summary.Checks["tls"].Status)
+
+	now = now.Add(2 * time.Minute)
+	code, summary = verboseHealth(t, server)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthFailing, summary.Status)
+	assert.Equal(t, HealthFailing, summary.Checks["tls"].Status)
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// jobGroupTask is the subset of a JobGroup task the webhook inspects.
+type jobGroupTask struct {
+	Name     string                 `json:"name"`
+	Replicas int32                  `json:"replicas"`
+	Template corev1.PodTemplateSpec `json:"template"`
+}
+
+// decodeTasks extracts spec.tasks from a raw JobGroup object.
+func decodeTasks(raw []byte) ([]jobGroupTask, error) {
+	var obj struct {
+		Spec struct {
+			Tasks []jobGroupTask `json:"tasks"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("invalid tasks: %w", err)
+	}
+	return obj.Spec.Tasks, nil
+}
+
+// checkNames reports a metadata.name that is not a DNS-1123 subdomain and a
+// namespace that is not a DNS-1123 label, citing the rule broken. The
+// namespace is taken from the object, falling back to the request's. Empty
+// values are left to the other checks.
+func checkNames(obj map[string]interface{}, requestNamespace string) []violation {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+
+	var violations []violation
+	if name, _ := metadata["name"].(string); name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			violations = append(violations, violation{
+				field:   "metadata.name",
+				message: fmt.Sprintf("metadata.name %q is invalid: %s", name, msg),
+			})
+		}
+	}
+
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" {
+		namespace = requestNamespace
+	}
+	if namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			violations = append(violations, violation{
+				field:   "metadata.namespace",
+				message: fmt.Sprintf("metadata.namespace %q is invalid: %s", namespace, msg),
+			})
+		}
+	}
+	return violations
+}
+
+// checkRequiredLabels reports the keys in required that metadata.labels lacks,
+// in one violation listing them all. A label with an empty value is present.
+func checkRequiredLabels(obj map[string]interface{}, required []string) []violation {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	labels, _ := metadata["labels"].(map[string]interface{})
+
+	var missing []string
+	for _, key := range required {
+		if _, ok := labels[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return []violation{{
+		field:   "metadata.labels",
+		message: fmt.Sprintf("missing required labels: %s", strings.Join(missing, ", ")),
+	}}
+}
+
+// checkTaskNames reports every task name used more than once, once per name.
+// Unnamed tasks are ignored.
+func checkTaskNames(tasks []jobGroupTask) []violation {
+	var violations []violation
+	seen := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		if task.Name == "" {
+			continue
+		}
+		seen[task.Name]++
+		if seen[task.Name] == 2 {
+			violations = append(violations, violation{
+				field:   fmt.Sprintf("spec.tasks[%d].name", i),
+				message: fmt.Sprintf("duplicate task name %q", task.Name),
+			})
+		}
+	}
+	return violations
+}
+
+// checkReplicaSum reports tasks whose replicas do not add up to maxMember.
+// Groups without tasks or without a maxMember are not checked.
+func checkReplicaSum(tasks []jobGroupTask, maxMember int64) []violation {
+	if len(tasks) == 0 || maxMember <= 0 {
+		return nil
+	}
+
+	var sum int64
+	for _, task := range tasks {
+		sum += int64(task.Replicas)
+	}
+	if sum == maxMember {
+		return nil
+	}
+	return []violation{{
+		field:   "spec.tasks",
+		message: fmt.Sprintf("task replicas sum to %d, but maxMember is %d", sum, maxMember),
+	}}
+}
+
+// checkContainerRequests reports every container request that exceeds its
+// maximum in limits.
+func checkContainerRequests(tasks []jobGroupTask, limits corev1.ResourceList) []violation {
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var violations []violation
+	for i, task := range tasks {
+		for j, container := range task.Template.Spec.Containers {
+			for _, name := range names {
+				limit := limits[corev1.ResourceName(name)]
+				request, ok := container.Resources.Requests[corev1.ResourceName(name)]
+				if ok && request.Cmp(limit) > 0 {
+					violations = append(violations, violation{
+						field: fmt.Sprintf("spec.tasks[%d].template.spec.containers[%d].resources.requests.%s", i, j, name),
+						message: fmt.Sprintf("task %q container %q requests %s=%s, above the per-container maximum %s",
+							task.Name, container.Name, name, request.String(), limit.String()),
+					})
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// checkAllowedResources reports every container request for a resource not
+// in allowed.
+func checkAllowedResources(tasks []jobGroupTask, allowed []corev1.ResourceName) []violation {
+	allowedSet := make(map[corev1.ResourceName]bool, len(allowed))
+	for _, name := range allowed {
+		allowedSet[name] = true
+	}
+
+	var violations []violation
+	for i, task := range tasks {
+		for j, container := range task.Template.Spec.Containers {
+			names := make([]string, 0, len(container.Resources.Requests))
+			for name := range container.Resources.Requests {
+				if !allowedSet[name] {
+					names = append(names, string(name))
+				}
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				violations = append(violations, violation{
+					field: fmt.Sprintf("spec.tasks[%d].template.spec.containers[%d].resources.requests.%s", i, j, name),
+					message: fmt.Sprintf("task %q container %q requests resource %q, which is not allowed",
+						task.Name, container.Name, name),
+				})
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,331 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
+)
+
+// Option configures a Server.
+type Option func(*Server)
+
+// configOption returns an Option applying fn to the admission policy. fn is
+// also re-applied to every config loaded by /reload, so settings made on top
+// of a WithConfig file survive a reload.
+func configOption(fn func(*Config)) Option {
+	return func(s *Server) {
+		fn(&s.config)
+		s.configOverrides = append(s.configOverrides, fn)
+	}
+}
+
+// WithPort sets the port the server listens on.
+func WithPort(port int) Option {
+	return func(s *Server) {
+		s.port = port
+	}
+}
+
+// WithTLS sets the certificate and key files used to serve TLS.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithCertificate serves cert, e.g. one from GenerateSelfSignedCert, instead
+// of a keypair read from the WithTLS files.
+func WithCertificate(cert tls.Certificate) Option {
+	return func(s *Server) {
+		s.certificate = &cert
+	}
+}
+
+// WithDebugPort serves the net/http/pprof profiles over plain HTTP on
+// 127.0.0.1:port, separate from the admission port. Zero, the default,
+// disables it.
+func WithDebugPort(port int) Option {
+	return func(s *Server) {
+		s.debugPort = port
+	}
+}
+
+// WithCertGracePeriod sets how long the TLS cert files may be unreadable
+// before the liveness check fails.
+func WithCertGracePeriod(d time.Duration) Option {
+	return func(s *Server) {
+		s.certGracePeriod = d
+	}
+}
+
+// WithDrainDelay sets how long the server keeps listening after shutdown
+// begins, with /readyz failing and new admission requests refused, before it
+// stops accepting connections. This gives the API server time to drop the
+// replica from the Service endpoints. The default is no delay.
+func WithDrainDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.drainDelay = d
+	}
+}
+
+// WithShutdownTimeout sets how long shutdown waits for in-flight requests
+// once the drain delay has passed; requests still running are then cut off.
+// The default is 30 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithHandshakeFailureThreshold sets how many TLS handshakes may fail without
+// an admission review in between before /readyz reports the replica unready;
+// the next admission review makes it ready again. Probe requests do not count,
+// as probes typically skip certificate verification. Zero or less disables
+// the check. The default is 5.
+func WithHandshakeFailureThreshold(n int) Option {
+	return func(s *Server) {
+		s.handshakeFailureThreshold = n
+	}
+}
+
+// WithLogger sets the server logger. A nil logger is ignored.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithAuditLogger sets the logger admission decisions are recorded on.
+// Decisions go to the server logger when unset.
+func WithAuditLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.audit = logger
+	}
+}
+
+// WithCollector enables metrics reporting through collector.
+func WithCollector(collector *metrics.Collector) Option {
+	return func(s *Server) {
+		s.collector = collector
+	}
+}
+
+// WithQueueChecker rejects JobGroups referencing a queue that does not exist
+// or whose minMember pods cannot fit in the queue's capacity.
+func WithQueueChecker(checker QueueChecker) Option {
+	return func(s *Server) {
+		s.queues = checker
+	}
+}
+
+// WithMaxConcurrentRequests limits how many admission requests are handled at
+// once; requests beyond the limit are refused with 429 Too Many Requests. Zero
+// or less means unlimited, the default.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(s *Server) {
+		s.slots = nil
+		if n > 0 {
+			s.slots = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithResponseCache caches up to size validation decisions by request UID
+// for ttl, so API server retries get the earlier decision. Mutations are
+// always recomputed, as a reinvoked mutating webhook sees the same UID with a
+// changed object. A size or ttl of zero, the default, disables the cache.
+func WithResponseCache(size int, ttl time.Duration) Option {
+	return func(s *Server) {
+		s.responses = newResponseCache(size, ttl)
+	}
+}
+
+// WithRequestCapture keeps the last n admission requests, as received, in
+// memory and serves them at /debug/requests on the loopback debug port, see
+// WithDebugPort, for inspecting disputed decisions. A request larger than
+// maxBytes, or 64 KiB when maxBytes is zero, is kept without its objects. The
+// captures hold object contents, so they are never served on the admission
+// port; enable them only while debugging. Zero n, the default, disables it.
+func WithRequestCapture(n, maxBytes int) Option {
+	return func(s *Server) {
+		s.captured = newRequestCapture(n, maxBytes)
+	}
+}
+
+// WithMutationDefaults sets the values applied by the mutating webhook.
+func WithMutationDefaults(defaults MutationDefaults) Option {
+	return configOption(func(c *Config) {
+		c.Defaults = defaults
+	})
+}
+
+// WithNamespaceFilter restricts the namespaces the webhook enforces policy in.
+func WithNamespaceFilter(filter NamespaceFilter) Option {
+	return configOption(func(c *Config) {
+		c.Namespaces = filter
+	})
+}
+
+// WithPriorityRange sets the inclusive range a JobGroup priority must fall in.
+func WithPriorityRange(minPriority, maxPriority int) Option {
+	return configOption(func(c *Config) {
+		c.MinPriority = minPriority
+		c.MaxPriority = maxPriority
+	})
+}
+
+// WithScheduleTimeoutRange sets the inclusive range a JobGroup
+// scheduleTimeoutSeconds must fall in. A zero maximum leaves it unbounded.
+func WithScheduleTimeoutRange(minSeconds, maxSeconds int) Option {
+	return configOption(func(c *Config) {
+		c.MinScheduleTimeoutSeconds = minSeconds
+		c.MaxScheduleTimeoutSeconds = maxSeconds
+	})
+}
+
+// WithClusterMaxMinMember caps minMember for every JobGroup, regardless of
+// its queue. Zero disables the cap.
+func WithClusterMaxMinMember(n int) Option {
+	return configOption(func(c *Config) {
+		c.ClusterMaxMinMember = n
+	})
+}
+
+// WithMaxTasks rejects JobGroups with more than n tasks. Zero disables the
+// cap.
+func WithMaxTasks(n int) Option {
+	return configOption(func(c *Config) {
+		c.MaxTasks = n
+	})
+}
+
+// WithFailOpen sets whether internal errors admit or deny the object; see
+// Config.FailOpen.
+func WithFailOpen(failOpen bool) Option {
+	return configOption(func(c *Config) {
+		c.FailOpen = failOpen
+	})
+}
+
+// WithMetricsEndpoint serves the WithCollector collector's metrics at
+// /metrics on the admission port, so the webhook pod needs no separate
+// metrics listener. It has no effect without a collector.
+func WithMetricsEndpoint() Option {
+	return func(s *Server) {
+		s.serveMetrics = true
+	}
+}
+
+// WithCheckedOperations makes the server check requests for ops, such as
+// DELETE, in addition to CREATE and UPDATE; a DELETE is checked against the
+// object being deleted, and only CREATE and UPDATE are ever mutated. Requests
+// for other operations are allowed without being decoded.
+func WithCheckedOperations(ops ...admissionv1.Operation) Option {
+	return func(s *Server) {
+		if s.extraOperations == nil {
+			s.extraOperations = make(map[admissionv1.Operation]bool, len(ops))
+		}
+		for _, op := range ops {
+			s.extraOperations[op] = true
+		}
+	}
+}
+
+// WithWarmupPeriod makes internal errors admit objects, with a warning, for d
+// after the server is created, whatever Config.FailOpen says, so a replica
+// whose dependencies are still connecting does not block every create. Zero,
+// the default, disables it.
+func WithWarmupPeriod(d time.Duration) Option {
+	return func(s *Server) {
+		s.warmupPeriod = d
+	}
+}
+
+// WithPatchTestGuards makes JSON patches assert the values they overwrite
+// with "test" operations; see Config.PatchTestGuards.
+func WithPatchTestGuards(enabled bool) Option {
+	return configOption(func(c *Config) {
+		c.PatchTestGuards = enabled
+	})
+}
+
+// WithMaxContainerRequests rejects JobGroups with a task container requesting
+// more of a resource than limits allows, e.g. more CPU than any node has.
+func WithMaxContainerRequests(limits corev1.ResourceList) Option {
+	return configOption(func(c *Config) {
+		c.MaxContainerRequests = limits
+	})
+}
+
+// WithStrictTaskReplicas requires the tasks' replicas to add up to maxMember
+// when both are set.
+func WithStrictTaskReplicas(enabled bool) Option {
+	return configOption(func(c *Config) {
+		c.StrictTaskReplicas = enabled
+	})
+}
+
+// WithAllowedResources rejects JobGroups with a task container requesting a
+// resource not in names, such as a vendor device the cluster does not offer.
+func WithAllowedResources(names ...corev1.ResourceName) Option {
+	return configOption(func(c *Config) {
+		c.AllowedResources = names
+	})
+}
+
+// WithRequiredLabels rejects JobGroups missing any of the label keys.
+func WithRequiredLabels(keys ...string) Option {
+	return configOption(func(c *Config) {
+		c.RequiredLabels = keys
+	})
+}
+
+// WithImmutableAnnotations denies UPDATEs that change or remove any of the
+// annotations keys once set.
+func WithImmutableAnnotations(keys ...string) Option {
+	return configOption(func(c *Config) {
+		c.ImmutableAnnotations = keys
+	})
+}
+
+// WithMetadataInjection sets the labels and annotations the mutator adds to
+// every admitted JobGroup.
+func WithMetadataInjection(inject MetadataInjection) Option {
+	return configOption(func(c *Config) {
+		c.Inject = inject
+	})
+}
+
+// WithSchema validates admitted objects against schema instead of the
+// built-in spec checks.
+func WithSchema(schema *Schema) Option {
+	return configOption(func(c *Config) {
+		c.Schema = schema
+	})
+}
+
+// WithConfig replaces the whole admission policy, discarding the settings of
+// earlier options; later ones apply on top of it and of every reload.
+func WithConfig(cfg Config) Option {
+	return func(s *Server) {
+		s.config = cfg
+		s.configOverrides = nil
+	}
+}
+
+// WithConfigReload enables POST /reload, which re-reads the config file at
+// path and swaps it in. Callers must present token as a bearer token.
+func WithConfigReload(path, token string) Option {
+	return func(s *Server) {
+		s.configFile = path
+		s.reloadToken = token
+	}
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Kinds the webhook knows how to admit.
+const (
+	kindJobGroup = "JobGroup"
+	kindQueue    = "Queue"
+)
+
+// validate routes a request to the validator for its kind. Requests without
+// a kind are treated as JobGroups; unknown kinds are allowed with a warning.
+// Operations the server does not check are allowed untouched.
+func (s *Server) validate(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if !s.checksOperation(req.Operation) {
+		return allowUncheckedOperation(logger, req)
+	}
+
+	switch req.Kind.Kind {
+	case kindJobGroup, "":
+		return s.validateJobGroup(logger, req)
+	case kindQueue:
+		return s.validateQueue(logger, req)
+	default:
+		return allowUnknownKind(logger, req)
+	}
+}
+
+// mutate routes a request to the mutator for its kind. Only JobGroups are
+// defaulted, and only on CREATE and UPDATE: other operations, even checked
+// ones, have no object to patch.
+func (s *Server) mutate(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	switch req.Operation {
+	case admissionv1.Create, admissionv1.Update, "":
+	default:
+		return allowUncheckedOperation(logger, req)
+	}
+
+	switch req.Kind.Kind {
+	case kindJobGroup, "":
+		return s.mutateJobGroup(logger, req)
+	case kindQueue:
+		return &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+	default:
+		return allowUnknownKind(logger, req)
+	}
+}
+
+// checksOperation reports whether requests for op are checked: CREATE and
+// UPDATE always are, as are requests that do not name an operation; others,
+// such as DELETE or CONNECT, only when enabled with WithCheckedOperations.
+func (s *Server) checksOperation(op admissionv1.Operation) bool {
+	switch op {
+	case admissionv1.Create, admissionv1.Update, "":
+		return true
+	default:
+		return s.extraOperations[op]
+	}
+}
+
+// admittedObject returns the object a request is validated against: the
+// object being deleted for a DELETE, which carries it only in OldObject, and
+// the submitted object otherwise.
+func admittedObject(req *admissionv1.AdmissionRequest) []byte {
+	if req.Operation == admissionv1.Delete {
+		return req.OldObject.Raw
+	}
+	return req.Object.Raw
+}
+
+// allowUncheckedOperation admits a request for an operation the server does
+// not check, typically sent by an over-broad webhook configuration, without
+// decoding its object.
+func allowUncheckedOperation(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger.Debug("allowing unchecked operation", "operation", req.Operation)
+	return &admissionv1.AdmissionResponse{UID: req.UID, Allowed: true}
+}
+
+func allowUnknownKind(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger.Warn("allowing object of unsupported kind", "kind", req.Kind.Kind)
+	return &admissionv1.AdmissionResponse{
+		UID:      req.UID,
+		Allowed:  true,
+		Warnings: []string{fmt.Sprintf("kind %q is not checked by the volcano admission webhook", req.Kind.Kind)},
+	}
+}
+
+// validateQueue checks that a Queue's weight and capacity are not negative.
+func (s *Server) validateQueue(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+
+	raw := admittedObject(req)
+	if len(raw) == 0 {
+		// A DELETE without the old object leaves nothing to check.
+		if req.Operation == admissionv1.Delete {
+			return response
+		}
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Message: fmt.Sprintf("no object to validate (operation %s)", req.Operation),
+		}
+		return response
+	}
+
+	var queue struct {
+		Spec struct {
+			Weight   *float64                     `json:"weight"`
+			Capacity map[string]resource.Quantity `json:"capacity"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &queue); err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Message: fmt.Sprintf("malformed object, failed to unmarshal: %v", err),
+			Reason:  metav1.StatusReasonBadRequest,
+			Code:    http.StatusBadRequest,
+		}
+		return response
+	}
+
+	var violations []violation
+
+	if weight := queue.Spec.Weight; weight != nil && *weight < 0 {
+		violations = append(violations, violation{
+			field:   "spec.weight",
+			message: fmt.Sprintf("weight %v must not be negative", *weight),
+		})
+	}
+
+	names := make([]string, 0, len(queue.Spec.Capacity))
+	for name := range queue.Spec.Capacity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if quantity := queue.Spec.Capacity[name]; quantity.Sign() < 0 {
+			violations = append(violations, violation{
+				field:   "spec.capacity." + name,
+				message: fmt.Sprintf("capacity %s=%s must not be negative", name, quantity.String()),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		response.Allowed = false
+		response.Result = violationStatus(violations)
+		return response
+	}
+
+	logger.Info("validation passed")
+	return response
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// admit sends obj of the given kind through the handler for path and
+// returns the decoded response.
+func admit(t *testing.T, server *Server, path, kind string, obj map[string]interface{}) *admissionv1.AdmissionResponse {
+	t.Helper()
+
+	raw, err := json.Marshal(obj)
+	require.NoError(t, err)
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:    types.UID(kind + path),
+			Kind:   metav1.GroupVersionKind{Group: "scheduling.volcano.sh", Version: "v1alpha1", Kind: kind},
+			Object: runtime.RawExtension{Raw: raw},
+		},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	if path == "/mutate" {
+		server.handleMutate(rec, newJSONRequest(path, body))
+	} else {
+		server.handleValidate(rec, newJSONRequest(path, body))
+	}
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var review admissionv1.AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	return review.Response
+}
+
+func TestValidate_RoutesByKind(t *testing.T) {
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithResponseCache(0, 0),
+	)
+
+	response := admit(t, server, "/validate", "Queue", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"weight":   -1,
+			"capacity": map[string]interface{}{"cpu": "-2", "memory": "8Gi"},
+		},
+	})
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "weight -1 must not be negative; capacity cpu=-2 must not be negative", response.Result.Message)
+
+	response = admit(t, server, "/validate", "Queue", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"weight":   1,
+			"capacity": map[string]interface{}{"cpu": 16, "memory": "64Gi"},
+		},
+	})
+	assert.True(t, response.Allowed)
+
+	// A Queue has no minMember, which the JobGroup rules would reject.
+	response = admit(t, server, "/validate", "JobGroup", map[string]interface{}{
+		"spec": map[string]interface{}{"capacity": map[string]interface{}{"cpu": 16}},
+	})
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, "minMember must be positive")
+
+	response = admit(t, server, "/validate", "Widget", map[string]interface{}{})
+	assert.True(t, response.Allowed)
+	assert.Equal(t, []string{`kind "Widget" is not checked by the volcano admission webhook`}, response.Warnings)
+}
+
+func TestMutate_OnlyDefaultsJobGroups(t *testing.T) {
+	server := NewServerWithOptions(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	obj := map[string]interface{}{"spec": map[string]interface{}{"minMember": 2}}
+
+	assert.NotEmpty(t, admit(t, server, "/mutate", "JobGroup", obj).Patch)
+
+	response := admit(t, server, "/mutate", "Queue", obj)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patch)
+}
+
+func TestHandlers_SkipUncheckedOperations(t *testing.T) {
+	requests := 0
+	review := func(server *Server, path string, op admissionv1.Operation, oldObject []byte) *admissionv1.AdmissionResponse {
+		requests++
+		body, err := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       types.UID(fmt.Sprintf("%s%s-%d", op, path, requests)),
+				Kind:      metav1.GroupVersionKind{Group: "scheduling.volcano.sh", Version: "v1alpha1", Kind: "JobGroup"},
+				Operation: op,
+				OldObject: runtime.RawExtension{Raw: oldObject},
+			},
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		if path == "/mutate" {
+			server.handleMutate(rec, newJSONRequest(path, body))
+		} else {
+			server.handleValidate(rec, newJSONRequest(path, body))
+		}
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var out admissionv1.AdmissionReview
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return out.Response
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// A DELETE carries no object and is allowed without decoding one.
+	server := NewServerWithOptions(WithLogger(logger))
+	for _, op := range []admissionv1.Operation{admissionv1.Delete, admissionv1.Connect} {
+		response := review(server, "/validate", op, nil)
+		assert.True(t, response.Allowed)
+		assert.Nil(t, response.Result)
+
+		response = review(server, "/mutate", op, nil)
+		assert.True(t, response.Allowed)
+		assert.Empty(t, response.Patch)
+	}
+
+	// A CREATE without an object is still denied.
+	assert.False(t, review(server, "/validate", admissionv1.Create, nil).Allowed)
+
+	// Once enabled, DELETE is validated against the object being deleted,
+	// which only the old object carries, and is never mutated.
+	server = NewServerWithOptions(WithLogger(logger), WithCheckedOperations(admissionv1.Delete))
+	valid := []byte(`{"metadata":{"name":"group"},"spec":{"minMember":2,"scheduleTimeoutSeconds":600}}`)
+	response := review(server, "/validate", admissionv1.Delete, valid)
+	assert.True(t, response.Allowed)
+	assert.Nil(t, response.Result)
+
+	response = review(server, "/validate", admissionv1.Delete, []byte(`{"metadata":{"name":"group"},"spec":{"minMember":0,"scheduleTimeoutSeconds":600}}`))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "minMember must be positive", response.Result.Message)
+
+	response = review(server, "/mutate", admissionv1.Delete, valid)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patch)
+
+	assert.True(t, review(server, "/validate", admissionv1.Connect, nil).Allowed)
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// QueueChecker looks up the queues JobGroups are submitted to, typically
+// backed by an informer lister.
+type QueueChecker interface {
+	// Exists reports whether the named queue exists.
+	Exists(name string) (bool, error)
+
+	// Capacity returns the named queue's capacity. Resources missing from
+	// the list are unbounded.
+	Capacity(name string) (corev1.ResourceList, error)
+}
+
+// checkQueue reports a JobGroup whose queue does not exist, or whose
+// minMember pods cannot fit in the queue's capacity even if each were as
+// small as the smallest pod of any task.
+func checkQueue(checker QueueChecker, queue string, minMember int64, tasks []jobGroupTask) ([]violation, error) {
+	exists, err := checker.Exists(queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up queue %q: %w", queue, err)
+	}
+	if !exists {
+		return []violation{{
+			field:   "spec.queue",
+			message: fmt.Sprintf("queue %q does not exist", queue),
+		}}, nil
+	}
+
+	if minMember <= 0 || len(tasks) == 0 {
+		return nil, nil
+	}
+
+	capacity, err := checker.Capacity(queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get capacity of queue %q: %w", queue, err)
+	}
+
+	names := make([]string, 0, len(capacity))
+	for name := range capacity {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	smallest := smallestPodRequests(tasks)
+
+	var violations []violation
+	for _, name := range names {
+		request, ok := smallest[corev1.ResourceName(name)]
+		if !ok {
+			continue
+		}
+		demand := request.DeepCopy()
+		demand.Mul(minMember)
+		limit := capacity[corev1.ResourceName(name)]
+		if demand.Cmp(limit) > 0 {
+			violations = append(violations, violation{
+				field: "spec.minMember",
+				message: fmt.Sprintf("minMember %d needs at least %s=%s, above queue %q capacity %s",
+					minMember, name, demand.String(), queue, limit.String()),
+			})
+		}
+	}
+	return violations, nil
+}
+
+// smallestPodRequests returns, per resource, the smallest total request of a
+// pod from any task. Resources some task's pods do not request are omitted.
+func smallestPodRequests(tasks []jobGroupTask) corev1.ResourceList {
+	var smallest corev1.ResourceList
+	for i, task := range tasks {
+		pod := corev1.ResourceList{}
+		for _, container := range task.Template.Spec.Containers {
+			for name, request := range container.Resources.Requests {
+				total := pod[name]
+				total.Add(request)
+				pod[name] = total
+			}
+		}
+
+		if i == 0 {
+			smallest = pod
+			continue
+		}
+		for name, request := range smallest {
+			other, ok := pod[name]
+			if !ok {
+				delete(smallest, name)
+			} else if other.Cmp(request) < 0 {
+				smallest[name] = other
+			}
+		}
+	}
+	return smallest
+}
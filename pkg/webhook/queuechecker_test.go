@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeQueues is a QueueChecker backed by a map of queue capacities.
+type fakeQueues struct {
+	capacity map[string]corev1.ResourceList
+	err      error
+}
+
+func (f *fakeQueues) Exists(name string) (bool, error) {
+	_, ok := f.capacity[name]
+	return ok, f.err
+}
+
+func (f *fakeQueues) Capacity(name string) (corev1.ResourceList, error) {
+	return f.capacity[name], f.err
+}
+
+func queueRequest(queue string, minMember int, cpu ...string) *admissionv1.AdmissionRequest {
+	tasks := make([]interface{}, 0, len(cpu))
+	for _, request := range cpu {
+		tasks = append(tasks, map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "main",
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"cpu": request},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"queue":                  queue,
+			"minMember":              minMember,
+			"scheduleTimeoutSeconds": 600,
+			"tasks":                  tasks,
+		},
+	})
+	return &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	}
+}
+
+func TestValidateJobGroup_QueueCapacity(t *testing.T) {
+	queues := &fakeQueues{capacity: map[string]corev1.ResourceList{
+		"small": {corev1.ResourceCPU: resource.MustParse("8")},
+	}}
+	server := NewServerWithOptions(WithQueueChecker(queues))
+
+	response := server.validateJobGroup(server.logger, queueRequest("small", 4, "2"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, queueRequest("small", 5, "2"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `minMember 5 needs at least cpu=10, above queue "small" capacity 8`, response.Result.Message)
+	assert.Equal(t, "spec.minMember", response.Result.Details.Causes[0].Field)
+
+	// Only the smallest pod counts, so mixing in a larger task still fits.
+	response = server.validateJobGroup(server.logger, queueRequest("small", 4, "500m", "4"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, queueRequest("missing", 1, "1"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `queue "missing" does not exist`, response.Result.Message)
+
+	response = NewServerWithOptions().validateJobGroup(server.logger, queueRequest("small", 5, "2"))
+	assert.True(t, response.Allowed)
+}
+
+func TestValidateJobGroup_QueueLookupFailure(t *testing.T) {
+	queues := &fakeQueues{err: errors.New("lister not synced")}
+
+	// Failing closed denies the group with an internal error.
+	server := NewServerWithOptions(WithQueueChecker(queues))
+	response := server.validateJobGroup(server.logger, queueRequest("gpu", 2, "1"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, metav1.StatusReasonInternalError, response.Result.Reason)
+	assert.Equal(t, int32(http.StatusInternalServerError), response.Result.Code)
+	assert.Contains(t, response.Result.Message, "lister not synced")
+
+	// Failing open admits it with a warning.
+	server = NewServerWithOptions(WithQueueChecker(queues), WithFailOpen(true))
+	response = server.validateJobGroup(server.logger, queueRequest("gpu", 2, "1"))
+	assert.True(t, response.Allowed)
+	require.Len(t, response.Warnings, 1)
+	assert.Contains(t, response.Warnings[0], "lister not synced")
+
+	// Violations found before the failure still deny the group.
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"queue": "gpu", "minMember": 0, "scheduleTimeoutSeconds": 600},
+	})
+	response = server.validateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	})
+	assert.False(t, response.Allowed)
+	assert.Equal(t, metav1.StatusReasonInvalid, response.Result.Reason)
+}
+
+func TestValidateJobGroup_WarmupFailsOpen(t *testing.T) {
+	queues := &fakeQueues{err: errors.New("lister not synced")}
+	server := NewServerWithOptions(WithQueueChecker(queues), WithWarmupPeriod(time.Minute))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.now = func() time.Time { return now }
+	server.startedAt = now
+
+	// Within the warm-up period internal errors admit the group.
+	now = now.Add(30 * time.Second)
+	response := server.validateJobGroup(server.logger, queueRequest("gpu", 2, "1"))
+	assert.True(t, response.Allowed)
+	require.Len(t, response.Warnings, 1)
+	assert.Contains(t, response.Warnings[0], "lister not synced")
+
+	// Afterwards the configured fail-closed behaviour applies again.
+	now = now.Add(time.Minute)
+	response = server.validateJobGroup(server.logger, queueRequest("gpu", 2, "1"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, metav1.StatusReasonInternalError, response.Result.Reason)
+}
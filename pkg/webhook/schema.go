@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Schema is a JSON Schema document used to validate admitted objects. Only
+// the keywords needed to describe CRD objects are supported: type,
+// properties, required, items, enum, minimum, maximum, minLength, maxLength,
+// minItems and maxItems. Other keywords are ignored.
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	MinItems   *int               `json:"minItems,omitempty"`
+	MaxItems   *int               `json:"maxItems,omitempty"`
+}
+
+// ParseSchema decodes a JSON Schema document.
+func ParseSchema(doc []byte) (*Schema, error) {
+	schema := &Schema{}
+	if err := json.Unmarshal(doc, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+// Validate checks value, as decoded by encoding/json, against the schema and
+// returns every violation found, each prefixed with the offending field path.
+func (sc *Schema) Validate(value interface{}) []string {
+	var violations []string
+	sc.validate("", value, &violations)
+	return violations
+}
+
+func (sc *Schema) validate(path string, value interface{}, violations *[]string) {
+	fail := func(format string, args ...interface{}) {
+		field := path
+		if field == "" {
+			field = "<root>"
+		}
+		*violations = append(*violations, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if sc.Type != "" && !matchesType(sc.Type, value) {
+		fail("must be of type %s", sc.Type)
+		return
+	}
+
+	if len(sc.Enum) > 0 && !inEnum(sc.Enum, value) {
+		fail("must be one of %v", sc.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range sc.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, joinPath(path, name)+": is required")
+			}
+		}
+
+		names := make([]string, 0, len(sc.Properties))
+		for name := range sc.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if field, ok := v[name]; ok {
+				sc.Properties[name].validate(joinPath(path, name), field, violations)
+			}
+		}
+
+	case []interface{}:
+		if sc.MinItems != nil && len(v) < *sc.MinItems {
+			fail("must have at least %d items", *sc.MinItems)
+		}
+		if sc.MaxItems != nil && len(v) > *sc.MaxItems {
+			fail("must have at most %d items", *sc.MaxItems)
+		}
+		if sc.Items != nil {
+			for i, item := range v {
+				sc.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+
+	case string:
+		if sc.MinLength != nil && len(v) < *sc.MinLength {
+			fail("must be at least %d characters", *sc.MinLength)
+		}
+		if sc.MaxLength != nil && len(v) > *sc.MaxLength {
+			fail("must be at most %d characters", *sc.MaxLength)
+		}
+
+	case float64:
+		if sc.Minimum != nil && v < *sc.Minimum {
+			fail("must be >= %v", *sc.Minimum)
+		}
+		if sc.Maximum != nil && v > *sc.Maximum {
+			fail("must be <= %v", *sc.Maximum)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
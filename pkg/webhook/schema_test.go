@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["spec"],
+	"properties": {
+		"spec": {
+			"type": "object",
+			"required": ["minMember", "team"],
+			"properties": {
+				"minMember": {"type": "integer", "minimum": 1},
+				"team": {"type": "string", "minLength": 1},
+				"mode": {"enum": ["batch", "service"]},
+				"tasks": {
+					"type": "array",
+					"maxItems": 2,
+					"items": {"type": "object", "required": ["name"]}
+				}
+			}
+		}
+	}
+}`
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		object     string
+		violations []string
+	}{
+		{
+			name:   "valid",
+			object: `{"spec": {"minMember": 3, "team": "ml", "mode": "batch", "tasks": [{"name": "a"}]}}`,
+		},
+		{
+			name:       "missing spec",
+			object:     `{"metadata": {}}`,
+			violations: []string{"spec: is required"},
+		},
+		{
+			name:   "multiple violations",
+			object: `{"spec": {"minMember": 0.5, "mode": "interactive", "tasks": [{}, {}, {"name": "c"}]}}`,
+			violations: []string{
+				"spec.team: is required",
+				"spec.minMember: must be of type integer",
+				"spec.mode: must be one of [batch service]",
+				"spec.tasks: must have at most 2 items",
+				"spec.tasks[0].name: is required",
+				"spec.tasks[1].name: is required",
+			},
+		},
+		{
+			name:       "wrong root type",
+			object:     `[]`,
+			violations: []string{"<root>: must be of type object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.object), &obj))
+			assert.Equal(t, tt.violations, schema.Validate(obj))
+		})
+	}
+}
+
+func TestParseSchema_Invalid(t *testing.T) {
+	_, err := ParseSchema([]byte(`{"type": 1}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid schema")
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelfSignedCert_Handshake(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSignedCert([]string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	server := NewServerWithOptions(WithCertificate(cert))
+	httpServer := httptest.NewUnstartedServer(server.routes())
+	httpServer.TLS = &tls.Config{Certificates: []tls.Certificate{*server.certificate}}
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certPEM))
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+
+	// httptest listens on 127.0.0.1, one of the certificate's hosts.
+	resp, err := client.Get(httpServer.URL + "/health")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, resp.TLS)
+	assert.Equal(t, []string{"localhost"}, resp.TLS.PeerCertificates[0].DNSNames)
+
+	// Nothing trusts the certificate by default.
+	_, err = (&http.Client{Timeout: 5 * time.Second}).Get(httpServer.URL + "/health")
+	assert.Error(t, err)
+}
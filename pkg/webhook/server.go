@@ -1,20 +1,40 @@
 package webhook
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"mime"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
 )
 
+// DefaultedFieldsAuditAnnotation is the audit annotation listing the spec
+// fields the mutator defaulted, e.g. "maxMember,priority". The API server
+// prefixes it with the webhook's name in the audit log.
+const DefaultedFieldsAuditAnnotation = "defaulted-fields"
+
 var (
 	scheme = runtime.NewScheme()
 	codecs = serializer.NewCodecFactory(scheme)
@@ -26,46 +46,144 @@ func init() {
 
 // Server is the admission webhook server.
 type Server struct {
-	port     int
-	certFile string
-	keyFile  string
-	logger   *slog.Logger
-	server   *http.Server
+	port      int
+	certFile  string
+	keyFile   string
+	logger    *slog.Logger
+	audit     *slog.Logger
+	collector *metrics.Collector
+	server    *http.Server
+
+	// certificate, when set, is served in place of certFile and keyFile.
+	certificate *tls.Certificate
+
+	// config is swapped atomically by /reload; handlers read it through
+	// currentConfig.
+	config      Config
+	configMu    sync.RWMutex
+	configFile  string
+	reloadToken string
+
+	// configOverrides are the option settings made on top of the config,
+	// re-applied to each reloaded one.
+	configOverrides []func(*Config)
+
+	// now is the clock, replaceable in tests.
+	now func() time.Time
+
+	// warmupPeriod is how long after startedAt internal errors fail open
+	// regardless of Config.FailOpen.
+	warmupPeriod time.Duration
+	startedAt    time.Time
+
+	// certGracePeriod is how long the cert files may stay unreadable before
+	// liveness fails; certUnreadableSince is when they were first found so.
+	certGracePeriod     time.Duration
+	certMu              sync.Mutex
+	certUnreadableSince time.Time
+
+	// debugPort, when positive, serves pprof on localhost over plain HTTP.
+	debugPort int
+
+	// serveMetrics mounts the collector's metrics at /metrics on the
+	// admission port.
+	serveMetrics bool
+
+	// queues, when set, checks the queue a JobGroup references.
+	queues QueueChecker
+
+	// extraOperations are checked in addition to CREATE and UPDATE.
+	extraOperations map[admissionv1.Operation]bool
+
+	// slots bounds concurrent admission requests; nil means unlimited.
+	slots chan struct{}
+
+	// responses caches recent decisions by request UID; nil disables it.
+	responses *responseCache
+
+	// captured keeps recent requests for /debug/requests; nil disables it.
+	captured *requestCapture
+
+	// handshakeFailureThreshold configures breaker, which fails readiness
+	// while TLS handshakes keep failing; nil disables it.
+	handshakeFailureThreshold int
+	breaker                   *handshakeBreaker
+
+	// draining is set once shutdown begins; new admission requests are then
+	// refused so the API server retries them on another replica.
+	draining atomic.Bool
+
+	// drainDelay is how long the server keeps listening after draining
+	// starts; shutdownTimeout then bounds the wait for in-flight requests.
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
 }
 
 // NewServer creates a new webhook server.
 func NewServer(port int, certFile, keyFile string, logger *slog.Logger) *Server {
-	if logger == nil {
-		logger = slog.Default()
+	return NewServerWithOptions(
+		WithPort(port),
+		WithTLS(certFile, keyFile),
+		WithLogger(logger),
+	)
+}
+
+// NewServerWithOptions creates a new webhook server configured by opts.
+func NewServerWithOptions(opts ...Option) *Server {
+	s := &Server{
+		port:                      8443,
+		logger:                    slog.Default(),
+		config:                    DefaultConfig(),
+		now:                       time.Now,
+		certGracePeriod:           2 * time.Minute,
+		handshakeFailureThreshold: defaultHandshakeFailureThreshold,
+		shutdownTimeout:           30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
-	return &Server{
-		port:     port,
-		certFile: certFile,
-		keyFile:  keyFile,
-		logger:   logger,
+	if s.audit == nil {
+		s.audit = s.logger
 	}
+	s.startedAt = s.now()
+	s.breaker = newHandshakeBreaker(s.handshakeFailureThreshold, s.logger)
+
+	return s
 }
 
 // Run starts the webhook server.
 func (s *Server) Run(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/validate", s.handleValidate)
-	mux.HandleFunc("/mutate", s.handleMutate)
-	mux.HandleFunc("/health", s.handleHealth)
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return s.certificate, nil
+	}
+	if s.certificate == nil {
+		// Load the keypair before listening so a bad one fails startup
+		// instead of surfacing later from the listener goroutine.
+		certs := newCertReloader(s.certFile, s.keyFile, s.logger, s.collector)
+		if _, err := certs.GetCertificate(nil); err != nil {
+			return err
+		}
+		getCertificate = certs.GetCertificate
+	}
 
-	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: mux,
-		TLSConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		},
+	s.server = s.newHTTPServer(s.routes(), getCertificate)
+
+	if debug := s.newDebugServer(); debug != nil {
+		go func() {
+			s.logger.Info("debug server listening", "addr", debug.Addr)
+			if err := debug.ListenAndServe(); err != http.ErrServerClosed {
+				s.logger.Error("debug server failed", "error", err)
+			}
+		}()
+		defer debug.Close()
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
 		s.logger.Info("webhook server listening", "port", s.port)
-		if err := s.server.ListenAndServeTLS(s.certFile, s.keyFile); err != http.ErrServerClosed {
+		if err := s.server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
@@ -74,140 +192,711 @@ func (s *Server) Run(ctx context.Context) error {
 	case err := <-errCh:
 		return err
 	case <-ctx.Done():
-		return s.server.Shutdown(context.Background())
+		return s.drain()
+	}
+}
+
+// drain fails readiness, then keeps listening for drainDelay so the API
+// server sees the replica leave the Service endpoints before connections are
+// refused, and finally waits up to shutdownTimeout for in-flight requests.
+// Requests still running after that are cut off.
+func (s *Server) drain() error {
+	s.draining.Store(true)
+	s.logger.Info("draining in-flight admission requests",
+		"delay", s.drainDelay, "timeout", s.shutdownTimeout)
+	time.Sleep(s.drainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		_ = s.server.Close()
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	return nil
+}
+
+// routes returns the admission server's routes.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", s.handleValidate)
+	mux.HandleFunc("/mutate", s.handleMutate)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/healthz/verbose", s.handleHealthVerbose)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/reload", s.handleReload)
+	mux.HandleFunc("/configz", s.handleConfigz)
+	if s.serveMetrics && s.collector != nil {
+		mux.Handle("/metrics", s.collector.MetricsHandler())
+	}
+	return mux
+}
+
+// debugRoutes returns the debug server's routes: pprof, and the captured
+// requests when capture is enabled. They expose object contents, so they are
+// only served on the loopback debug port.
+func (s *Server) debugRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", metrics.DebugHandler())
+	if s.captured != nil {
+		mux.HandleFunc("/debug/requests", s.handleCapturedRequests)
+	}
+	return mux
+}
+
+// newDebugServer returns the plain HTTP debug server on the loopback debug
+// port, or nil when it is disabled.
+func (s *Server) newDebugServer() *http.Server {
+	if s.debugPort <= 0 {
+		return nil
+	}
+	return &http.Server{
+		Addr:    metrics.DebugAddr(s.debugPort),
+		Handler: s.debugRoutes(),
+	}
+}
+
+// newHTTPServer returns the TLS server for handler, serving certificates from
+// getCertificate and reporting handshake failures to the breaker.
+func (s *Server) newHTTPServer(handler http.Handler, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *http.Server {
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.port),
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: getCertificate,
+		},
+		ErrorLog: httpErrorLog(s.logger, s.breaker),
 	}
 }
 
 func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
-	s.logger.Debug("received validation request")
+	defer s.trackInflight()()
+
+	if s.rejectIfDraining(w) {
+		return
+	}
+
+	release, ok := s.acquireSlot(w)
+	if !ok {
+		return
+	}
+	defer release()
 
 	review, err := s.parseAdmissionReview(r)
 	if err != nil {
-		s.logger.Error("failed to parse admission review", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.rejectRequest(w, r, err)
+		return
+	}
+
+	s.breaker.success()
+
+	logger := s.requestLogger(review.Request)
+	logger.Debug("received validation request")
+	s.countRequest(r, review.Request)
+	s.captureRequest(r, review.Request)
+
+	if s.respondFromCache(w, r, review, logger) {
+		s.auditDecision(review.Request, review.Response, true)
 		return
 	}
 
-	response := s.validateJobGroup(review.Request)
+	response := s.validate(logger, review.Request)
 	review.Response = response
+	s.auditDecision(review.Request, response, false)
+	s.cacheResponse(review)
 
-	s.writeResponse(w, review)
+	s.writeResponse(w, r, review)
 }
 
 func (s *Server) handleMutate(w http.ResponseWriter, r *http.Request) {
-	s.logger.Debug("received mutation request")
+	defer s.trackInflight()()
+
+	if s.rejectIfDraining(w) {
+		return
+	}
+
+	release, ok := s.acquireSlot(w)
+	if !ok {
+		return
+	}
+	defer release()
 
 	review, err := s.parseAdmissionReview(r)
 	if err != nil {
-		s.logger.Error("failed to parse admission review", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.rejectRequest(w, r, err)
 		return
 	}
 
-	response := s.mutateJobGroup(review.Request)
-	review.Response = response
+	s.breaker.success()
 
-	s.writeResponse(w, review)
+	logger := s.requestLogger(review.Request)
+	logger.Debug("received mutation request")
+	s.countRequest(r, review.Request)
+	s.captureRequest(r, review.Request)
+
+	// Mutations are never served from the response cache: a reinvoked
+	// mutating webhook gets the same UID with an object other webhooks have
+	// since changed, which an earlier patch would overwrite.
+	review.Response = s.mutate(logger, review.Request)
+
+	s.writeResponse(w, r, review)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkCertFiles(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// checkCertFiles reports an error once the TLS cert or key has been
+// unreadable for longer than the grace period, so the kubelet restarts a pod
+// whose mounted secret went bad instead of letting handshakes fail later.
+func (s *Server) checkCertFiles() error {
+	if s.certFile == "" {
+		return nil
+	}
+
+	err := readable(s.certFile)
+	if err == nil {
+		err = readable(s.keyFile)
+	}
+
+	s.certMu.Lock()
+	defer s.certMu.Unlock()
+
+	if err == nil {
+		s.certUnreadableSince = time.Time{}
+		return nil
+	}
+
+	now := s.now()
+	if s.certUnreadableSince.IsZero() {
+		s.certUnreadableSince = now
+		s.logger.Warn("TLS cert files unreadable", "error", err)
+	}
+
+	if unreadable := now.Sub(s.certUnreadableSince); unreadable > s.certGracePeriod {
+		return fmt.Errorf("TLS cert files unreadable for %s: %w", unreadable.Round(time.Second), err)
+	}
+	return nil
+}
+
+// readable checks that path can be opened for reading.
+func readable(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	if s.breaker.isOpen() {
+		http.Error(w, "TLS handshakes failing", http.StatusServiceUnavailable)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
+// handleReload re-reads the config file and swaps it in. The request must
+// carry the configured reload token as a bearer token. An invalid config is
+// rejected and the running one kept.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.configFile == "" || s.reloadToken == "" {
+		http.Error(w, "reload is not enabled", http.StatusNotFound)
+		return
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.reloadToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cfg, err := LoadConfig(s.configFile)
+	if err != nil {
+		s.logger.Error("config reload failed, keeping current config", "error", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	for _, override := range s.configOverrides {
+		override(&cfg)
+	}
+
+	s.configMu.Lock()
+	s.config = cfg
+	s.configMu.Unlock()
+
+	s.logger.Info("reloaded config", "file", s.configFile)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("reloaded"))
+}
+
+// respondFromCache answers a retried validation request with the decision
+// cached for its UID, reporting whether it did.
+func (s *Server) respondFromCache(w http.ResponseWriter, r *http.Request, review *admissionv1.AdmissionReview, logger *slog.Logger) bool {
+	if review.Request.UID == "" {
+		return false
+	}
+
+	response, ok := s.responses.get(string(review.Request.UID), s.now())
+	if !ok {
+		return false
+	}
+
+	logger.Debug("returning cached decision")
+	review.Response = response
+	s.writeResponse(w, r, review)
+	return true
+}
+
+// cacheResponse remembers the decision in review for retries of the request.
+func (s *Server) cacheResponse(review *admissionv1.AdmissionReview) {
+	if review.Request.UID == "" {
+		return
+	}
+
+	s.responses.put(string(review.Request.UID), review.Response, s.now())
+}
+
+// currentConfig returns the config in effect. Each request should read it
+// once so a concurrent reload cannot mix two configs.
+func (s *Server) currentConfig() Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	return s.config
+}
+
+// rejectIfDraining answers 503 when the server is shutting down, letting the
+// API server retry the request against another replica.
+func (s *Server) rejectIfDraining(w http.ResponseWriter) bool {
+	if !s.draining.Load() {
+		return false
+	}
+
+	http.Error(w, "webhook is draining", http.StatusServiceUnavailable)
+	return true
+}
+
+// trackInflight counts a request as in flight until the returned func is
+// called.
+func (s *Server) trackInflight() func() {
+	if s.collector == nil {
+		return func() {}
+	}
+
+	s.collector.IncWebhookInflightRequests()
+	return s.collector.DecWebhookInflightRequests
+}
+
+// acquireSlot reserves one of the concurrent request slots. When all are
+// taken it answers 429 so the API server backs off and retries, and reports
+// false. The returned func frees the slot.
+func (s *Server) acquireSlot(w http.ResponseWriter) (func(), bool) {
+	if s.slots == nil {
+		return func() {}, true
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, true
+	default:
+		s.logger.Warn("too many concurrent admission requests", "limit", cap(s.slots))
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many concurrent admission requests", http.StatusTooManyRequests)
+		return nil, false
+	}
+}
+
+// requestError is a request failure that maps to a specific HTTP status.
+type requestError struct {
+	code int
+	err  error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// reviewError is an admission review that was read but failed to decode,
+// with the request UID and apiVersion salvaged from its body, if any, so the
+// denial can still be matched to the request.
+type reviewError struct {
+	uid        types.UID
+	apiVersion string
+	err        error
+}
+
+func (e *reviewError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reviewError) Unwrap() error {
+	return e.err
+}
+
+// newReviewError wraps err, salvaging what identifies the review from body.
+func newReviewError(body []byte, err error) *reviewError {
+	var partial struct {
+		APIVersion string `json:"apiVersion"`
+		Request    struct {
+			UID types.UID `json:"uid"`
+		} `json:"request"`
+	}
+	_ = json.Unmarshal(body, &partial)
+
+	reviewErr := &reviewError{uid: partial.Request.UID, apiVersion: admissionv1.SchemeGroupVersion.String(), err: err}
+	if partial.APIVersion == "admission.k8s.io/v1beta1" {
+		reviewErr.apiVersion = partial.APIVersion
+	}
+	return reviewErr
+}
+
+// rejectRequest answers a request whose admission review could not be parsed.
+// Protocol errors, such as a wrong content type, get a plain HTTP error. A
+// body that fails to decode but still names its request UID gets a denied
+// AdmissionReview for that UID with status 200, so the API server shows the
+// reason to the user instead of a generic webhook failure; without a UID the
+// API server would discard the review, so it gets a 400 instead.
+func (s *Server) rejectRequest(w http.ResponseWriter, r *http.Request, err error) {
+	s.logger.Error("failed to parse admission review", "error", err)
+	if s.collector != nil {
+		s.collector.IncWebhookParseErrors(r.URL.Path)
+	}
+
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		http.Error(w, err.Error(), reqErr.code)
+		return
+	}
+
+	var reviewErr *reviewError
+	if !errors.As(err, &reviewErr) || reviewErr.uid == "" {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.writeResponse(w, r, &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: reviewErr.apiVersion,
+			Kind:       "AdmissionReview",
+		},
+		Response: &admissionv1.AdmissionResponse{
+			UID:     reviewErr.uid,
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: err.Error(),
+				Reason:  metav1.StatusReasonBadRequest,
+				Code:    http.StatusBadRequest,
+			},
+		},
+	})
+}
+
 func (s *Server) parseAdmissionReview(r *http.Request) (*admissionv1.AdmissionReview, error) {
 	if r.Method != http.MethodPost {
-		return nil, fmt.Errorf("invalid method: %s", r.Method)
+		return nil, &requestError{
+			code: http.StatusMethodNotAllowed,
+			err:  fmt.Errorf("invalid method: %s", r.Method),
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return nil, &requestError{
+			code: http.StatusUnsupportedMediaType,
+			err:  fmt.Errorf("unsupported content type %q, expected application/json", r.Header.Get("Content-Type")),
+		}
+	}
+
+	if !acceptsJSON(r.Header.Get("Accept")) {
+		return nil, &requestError{
+			code: http.StatusNotAcceptable,
+			err:  fmt.Errorf("unacceptable response type %q, only application/json is served", r.Header.Get("Accept")),
+		}
+	}
+
+	defer r.Body.Close()
+
+	var reader io.Reader = r.Body
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt gzip body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	default:
+		return nil, &requestError{
+			code: http.StatusUnsupportedMediaType,
+			err:  fmt.Errorf("unsupported content encoding %q, expected gzip or none", encoding),
+		}
 	}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read body: %w", err)
 	}
-	defer r.Body.Close()
 
 	review := &admissionv1.AdmissionReview{}
 	if _, _, err := codecs.UniversalDeserializer().Decode(body, nil, review); err != nil {
-		return nil, fmt.Errorf("failed to decode body: %w", err)
+		return nil, newReviewError(body, fmt.Errorf("failed to decode body: %w", err))
+	}
+
+	if review.Request == nil {
+		return nil, newReviewError(body, fmt.Errorf("admission review has no request"))
 	}
 
 	return review, nil
 }
 
-func (s *Server) writeResponse(w http.ResponseWriter, review *admissionv1.AdmissionReview) {
+// countRequest records req in the request metrics.
+func (s *Server) countRequest(r *http.Request, req *admissionv1.AdmissionRequest) {
+	if s.collector != nil {
+		s.collector.IncWebhookRequests(r.URL.Path, string(req.Operation), req.DryRun != nil && *req.DryRun)
+	}
+}
+
+// requestLogger returns a logger carrying the identity of req so every line
+// logged while handling it can be correlated.
+func (s *Server) requestLogger(req *admissionv1.AdmissionRequest) *slog.Logger {
+	return s.logger.With("uid", req.UID, "namespace", req.Namespace, "name", req.Name)
+}
+
+// auditDecision records the outcome of an admission decision on the audit
+// logger. A decision replayed from the response cache is marked cached, so
+// every admission request, retries included, has exactly one record.
+func (s *Server) auditDecision(req *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse, cached bool) {
+	attrs := []any{
+		"uid", req.UID,
+		"namespace", req.Namespace,
+		"name", req.Name,
+		"operation", req.Operation,
+		"allowed", response.Allowed,
+	}
+	if !response.Allowed && response.Result != nil {
+		attrs = append(attrs, "reason", response.Result.Message)
+	}
+	if cached {
+		attrs = append(attrs, "cached", true)
+	}
+
+	s.audit.Info("admission decision", attrs...)
+}
+
+// acceptsJSON reports whether an Accept header admits an application/json
+// response. An absent header accepts anything.
+func acceptsJSON(accept string) bool {
+	if accept == "" {
+		return true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// writeResponse encodes review as JSON, indented when the request asks for
+// ?pretty=true.
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, review *admissionv1.AdmissionReview) {
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(review); err != nil {
+
+	encoder := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(review); err != nil {
 		s.logger.Error("failed to encode response", "error", err)
 	}
 }
 
-func (s *Server) validateJobGroup(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+func (s *Server) validateJobGroup(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
 	response := &admissionv1.AdmissionResponse{
 		UID:     req.UID,
 		Allowed: true,
 	}
 
-	var spec map[string]interface{}
-	if err := json.Unmarshal(req.Object.Raw, &spec); err != nil {
-		response.Allowed = false
-		response.Result = &metav1.Status{
-			Message: fmt.Sprintf("failed to unmarshal spec: %v", err),
-		}
+	cfg := s.currentConfig()
+	if !cfg.Namespaces.Matches(req.Namespace) {
 		return response
 	}
 
-	specData, ok := spec["spec"].(map[string]interface{})
-	if !ok {
+	raw := admittedObject(req)
+	if len(raw) == 0 {
+		// A DELETE without the old object leaves nothing to check.
+		if req.Operation == admissionv1.Delete {
+			return response
+		}
 		response.Allowed = false
 		response.Result = &metav1.Status{
-			Message: "spec field is required",
+			Message: fmt.Sprintf("no object to validate (operation %s)", req.Operation),
 		}
 		return response
 	}
 
-	// Validate minMember
-	minMember, _ := specData["minMember"].(float64)
-	if minMember <= 0 {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
 		response.Allowed = false
 		response.Result = &metav1.Status{
-			Message: "minMember must be positive",
+			Message: fmt.Sprintf("malformed object, failed to unmarshal: %v", err),
+			Reason:  metav1.StatusReasonBadRequest,
+			Code:    http.StatusBadRequest,
 		}
 		return response
 	}
 
-	// Validate maxMember
-	maxMember, _ := specData["maxMember"].(float64)
-	if maxMember > 0 && maxMember < minMember {
-		response.Allowed = false
-		response.Result = &metav1.Status{
-			Message: "maxMember must be >= minMember",
+	violations := checkObject(cfg, spec)
+	violations = append(violations, checkNames(spec, req.Namespace)...)
+	violations = append(violations, checkRequiredLabels(spec, cfg.RequiredLabels)...)
+
+	// internalErr is a failure of the webhook itself rather than of the
+	// object; it decides the response only if no violation was found.
+	var internalErr error
+
+	// Validate priority
+	specData, _ := spec["spec"].(map[string]interface{})
+	if priority, ok := specData["priority"].(float64); ok {
+		if priority < float64(cfg.MinPriority) || priority > float64(cfg.MaxPriority) {
+			violations = append(violations, violation{
+				field: "spec.priority",
+				message: fmt.Sprintf("priority %d must be between %d and %d",
+					int64(priority), cfg.MinPriority, cfg.MaxPriority),
+			})
 		}
-		return response
 	}
 
-	// Validate scheduleTimeoutSeconds
-	timeout, _ := specData["scheduleTimeoutSeconds"].(float64)
-	if timeout <= 0 {
-		response.Allowed = false
-		response.Result = &metav1.Status{
-			Message: "scheduleTimeoutSeconds must be positive",
+	// Validate minMember against the cluster-wide cap
+	if minMember, ok := specData["minMember"].(float64); ok && cfg.ClusterMaxMinMember > 0 &&
+		minMember > float64(cfg.ClusterMaxMinMember) {
+		violations = append(violations, violation{
+			field: "spec.minMember",
+			message: fmt.Sprintf("minMember %d is above the cluster-wide maximum of %d",
+				int64(minMember), cfg.ClusterMaxMinMember),
+		})
+	}
+
+	// Validate scheduleTimeoutSeconds bounds; non-positive values are
+	// reported by the structural checks.
+	if timeout, ok := specData["scheduleTimeoutSeconds"].(float64); ok && timeout > 0 {
+		switch {
+		case timeout < float64(cfg.MinScheduleTimeoutSeconds):
+			violations = append(violations, violation{
+				field: "spec.scheduleTimeoutSeconds",
+				message: fmt.Sprintf("scheduleTimeoutSeconds %d is below the minimum of %d",
+					int64(timeout), cfg.MinScheduleTimeoutSeconds),
+			})
+		case cfg.MaxScheduleTimeoutSeconds > 0 && timeout > float64(cfg.MaxScheduleTimeoutSeconds):
+			violations = append(violations, violation{
+				field: "spec.scheduleTimeoutSeconds",
+				message: fmt.Sprintf("scheduleTimeoutSeconds %d is above the maximum of %d",
+					int64(timeout), cfg.MaxScheduleTimeoutSeconds),
+			})
+		}
+	}
+
+	// Validate tasks
+	tasks, err := decodeTasks(raw)
+	if err != nil {
+		violations = append(violations, violation{field: "spec.tasks", message: err.Error()})
+	} else {
+		if cfg.MaxTasks > 0 && len(tasks) > cfg.MaxTasks {
+			violations = append(violations, violation{
+				field:   "spec.tasks",
+				message: fmt.Sprintf("%d tasks exceed the maximum of %d", len(tasks), cfg.MaxTasks),
+			})
+		}
+		violations = append(violations, checkTaskNames(tasks)...)
+		if len(cfg.MaxContainerRequests) > 0 {
+			violations = append(violations, checkContainerRequests(tasks, cfg.MaxContainerRequests)...)
+		}
+		if len(cfg.AllowedResources) > 0 {
+			violations = append(violations, checkAllowedResources(tasks, cfg.AllowedResources)...)
+		}
+		if cfg.StrictTaskReplicas {
+			maxMember, _ := specData["maxMember"].(float64)
+			violations = append(violations, checkReplicaSum(tasks, int64(maxMember))...)
 		}
+
+		// Validate the queue
+		if queue, _ := specData["queue"].(string); queue != "" && s.queues != nil {
+			minMember, _ := specData["minMember"].(float64)
+			var queueViolations []violation
+			queueViolations, internalErr = checkQueue(s.queues, queue, int64(minMember), tasks)
+			violations = append(violations, queueViolations...)
+		}
+	}
+
+	// Validate changes against the stored object
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		var oldSpec map[string]interface{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldSpec); err != nil {
+			violations = append(violations, violation{message: fmt.Sprintf("failed to unmarshal old object: %v", err)})
+		} else {
+			violations = append(violations, checkUpdate(oldSpec, spec)...)
+			violations = append(violations, checkImmutableAnnotations(oldSpec, spec, cfg.ImmutableAnnotations)...)
+		}
+	}
+
+	if len(violations) > 0 {
+		response.Allowed = false
+		response.Result = violationStatus(violations)
 		return response
 	}
+	if internalErr != nil {
+		return s.internalErrorResponse(cfg, logger, response, internalErr)
+	}
 
-	s.logger.Info("validation passed", "namespace", req.Namespace, "name", req.Name)
+	logger.Info("validation passed")
 	return response
 }
 
-func (s *Server) mutateJobGroup(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+func (s *Server) mutateJobGroup(logger *slog.Logger, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
 	response := &admissionv1.AdmissionResponse{
 		UID:     req.UID,
 		Allowed: true,
 	}
 
+	cfg := s.currentConfig()
+	if !cfg.Namespaces.Matches(req.Namespace) {
+		return response
+	}
+
+	defaults := cfg.Defaults
+
 	var spec map[string]interface{}
 	if err := json.Unmarshal(req.Object.Raw, &spec); err != nil {
-		s.logger.Error("failed to unmarshal for mutation", "error", err)
+		logger.Error("failed to unmarshal for mutation", "error", err)
 		return response
 	}
 
@@ -216,36 +905,344 @@ func (s *Server) mutateJobGroup(req *admissionv1.AdmissionRequest) *admissionv1.
 		return response
 	}
 
-	modified := false
+	defaulted := make(map[string]interface{})
 
 	// Set default maxMember if not specified
 	if _, exists := specData["maxMember"]; !exists {
 		minMember, _ := specData["minMember"].(float64)
-		specData["maxMember"] = minMember * 2
-		modified = true
+		defaulted["maxMember"] = minMember * float64(defaults.MaxMemberFactor)
 	}
 
 	// Set default priority if not specified
 	if _, exists := specData["priority"]; !exists {
-		specData["priority"] = 50
-		modified = true
+		defaulted["priority"] = defaults.Priority
 	}
 
 	// Set default timeout if not specified
 	if _, exists := specData["scheduleTimeoutSeconds"]; !exists {
-		specData["scheduleTimeoutSeconds"] = 600
-		modified = true
+		defaulted["scheduleTimeoutSeconds"] = defaults.ScheduleTimeoutSeconds
 	}
 
-	if modified {
-		patchedSpec, _ := json.Marshal(spec)
-		patch := []byte(fmt.Sprintf(`[{"op":"replace","path":"/spec","value":%s}]`, string(patchedSpec)))
-		response.Patch = patch
+	original := maps.Clone(specData)
+	for field, value := range defaulted {
+		specData[field] = value
+	}
+
+	metadata, _ := spec["metadata"].(map[string]interface{})
+	labels, annotations := injectedMetadata(cfg.Inject, metadata, defaulted)
+
+	if len(defaulted) > 0 || len(labels) > 0 || len(annotations) > 0 {
+		patch, err := buildPatch(jobGroupMutation{
+			metadata:    metadata,
+			original:    original,
+			specData:    specData,
+			defaulted:   defaulted,
+			labels:      labels,
+			annotations: annotations,
+			guard:       cfg.PatchTestGuards,
+		})
+		if err != nil {
+			return s.internalErrorResponse(cfg, logger, response, fmt.Errorf("failed to build patch: %w", err))
+		}
 		patchType := admissionv1.PatchTypeJSONPatch
+		response.Patch = patch
 		response.PatchType = &patchType
+		if len(defaulted) > 0 {
+			response.AuditAnnotations = map[string]string{
+				DefaultedFieldsAuditAnnotation: defaultedFields(defaulted),
+			}
+		}
+		if s.collector != nil {
+			s.collector.ObserveMutationPatchSize(len(patch))
+		}
 
-		s.logger.Info("applied default values", "namespace", req.Namespace, "name", req.Name)
+		logger.Info("applied default values")
 	}
 
 	return response
 }
+
+// internalErrorResponse completes response for an error inside the webhook,
+// such as an unreachable queue lister. With cfg.FailOpen the object is
+// admitted with a warning; otherwise it is denied, matching a failurePolicy
+// of Ignore or Fail respectively. During the warm-up period after startup it
+// is always admitted, since dependencies may not be reachable yet.
+func (s *Server) internalErrorResponse(cfg Config, logger *slog.Logger, response *admissionv1.AdmissionResponse, err error) *admissionv1.AdmissionResponse {
+	warmingUp := s.warmingUp()
+	logger.Error("internal admission error", "error", err, "failOpen", cfg.FailOpen, "warmingUp", warmingUp)
+	if warmingUp && !cfg.FailOpen {
+		logger.Warn("failing open during warm-up period", "warmupPeriod", s.warmupPeriod)
+	}
+
+	if cfg.FailOpen || warmingUp {
+		response.Allowed = true
+		response.Warnings = append(response.Warnings, fmt.Sprintf("admitted without full checks: %v", err))
+		return response
+	}
+
+	response.Allowed = false
+	response.Result = &metav1.Status{
+		Message: fmt.Sprintf("internal error: %v", err),
+		Reason:  metav1.StatusReasonInternalError,
+		Code:    http.StatusInternalServerError,
+	}
+	return response
+}
+
+// warmingUp reports whether the server is still within its warm-up period.
+func (s *Server) warmingUp() bool {
+	return s.warmupPeriod > 0 && s.now().Sub(s.startedAt) < s.warmupPeriod
+}
+
+// violation is a single validation failure. field is the path of the
+// offending field, or empty when the failure is not tied to one.
+type violation struct {
+	field   string
+	message string
+}
+
+// violationStatus reports every violation in one Invalid (422) denial: the
+// messages are joined into the status message and listed as causes with their
+// field paths.
+func violationStatus(violations []violation) *metav1.Status {
+	messages := make([]string, 0, len(violations))
+	causes := make([]metav1.StatusCause, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.message)
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: v.message,
+			Field:   v.field,
+		})
+	}
+
+	return &metav1.Status{
+		Message: strings.Join(messages, "; "),
+		Reason:  metav1.StatusReasonInvalid,
+		Code:    http.StatusUnprocessableEntity,
+		Details: &metav1.StatusDetails{Causes: causes},
+	}
+}
+
+// checkObject validates the structure of a JobGroup against the configured
+// schema, falling back to the built-in spec checks when there is none.
+func checkObject(cfg Config, obj map[string]interface{}) []violation {
+	if cfg.Schema != nil {
+		if violations := cfg.Schema.Validate(obj); len(violations) > 0 {
+			return []violation{{
+				message: fmt.Sprintf("schema validation failed: %s", strings.Join(violations, "; ")),
+			}}
+		}
+		return nil
+	}
+
+	specData, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		// Without a spec there is nothing else to check; report any other
+		// missing identity along with it.
+		violations := []violation{{field: "spec", message: "spec field is required"}}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if name, _ := metadata["name"].(string); name == "" {
+			violations = append(violations, violation{field: "metadata.name", message: "metadata.name is required"})
+		}
+		return violations
+	}
+
+	var violations []violation
+
+	// Validate minMember
+	minMember, _ := specData["minMember"].(float64)
+	if minMember <= 0 {
+		violations = append(violations, violation{field: "spec.minMember", message: "minMember must be positive"})
+	}
+
+	// Validate maxMember
+	maxMember, _ := specData["maxMember"].(float64)
+	if maxMember > 0 && maxMember < minMember {
+		violations = append(violations, violation{field: "spec.maxMember", message: "maxMember must be >= minMember"})
+	}
+
+	// Validate scheduleTimeoutSeconds
+	timeout, _ := specData["scheduleTimeoutSeconds"].(float64)
+	if timeout <= 0 {
+		violations = append(violations, violation{
+			field:   "spec.scheduleTimeoutSeconds",
+			message: "scheduleTimeoutSeconds must be positive",
+		})
+	}
+
+	return violations
+}
+
+// checkUpdate validates an UPDATE of oldObj to obj: spec.queue cannot change
+// once set, and minMember cannot drop below the members already running.
+func checkUpdate(oldObj, obj map[string]interface{}) []violation {
+	oldSpec, _ := oldObj["spec"].(map[string]interface{})
+	newSpec, _ := obj["spec"].(map[string]interface{})
+	oldStatus, _ := oldObj["status"].(map[string]interface{})
+
+	var violations []violation
+
+	if oldQueue, ok := oldSpec["queue"].(string); ok && oldQueue != "" {
+		if newQueue, _ := newSpec["queue"].(string); newQueue != oldQueue {
+			violations = append(violations, violation{
+				field:   "spec.queue",
+				message: fmt.Sprintf("spec.queue is immutable (was %q)", oldQueue),
+			})
+		}
+	}
+
+	running, _ := oldStatus["running"].(float64)
+	minMember, _ := newSpec["minMember"].(float64)
+	oldMinMember, _ := oldSpec["minMember"].(float64)
+	if minMember < oldMinMember && minMember < running {
+		violations = append(violations, violation{
+			field: "spec.minMember",
+			message: fmt.Sprintf("minMember %d cannot be lowered below the %d running members",
+				int64(minMember), int64(running)),
+		})
+	}
+
+	return violations
+}
+
+// checkImmutableAnnotations reports the keys whose annotation an UPDATE of
+// oldObj to obj changes or removes. A key the old object lacked may be added.
+func checkImmutableAnnotations(oldObj, obj map[string]interface{}, keys []string) []violation {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	oldMetadata, _ := oldObj["metadata"].(map[string]interface{})
+	newMetadata, _ := obj["metadata"].(map[string]interface{})
+	oldAnnotations, _ := oldMetadata["annotations"].(map[string]interface{})
+	newAnnotations, _ := newMetadata["annotations"].(map[string]interface{})
+
+	var violations []violation
+	for _, key := range keys {
+		oldValue, ok := oldAnnotations[key].(string)
+		if !ok {
+			continue
+		}
+		if newValue, ok := newAnnotations[key].(string); !ok || newValue != oldValue {
+			violations = append(violations, violation{
+				field:   fmt.Sprintf("metadata.annotations[%s]", key),
+				message: fmt.Sprintf("annotation %q is immutable (was %q)", key, oldValue),
+			})
+		}
+	}
+	return violations
+}
+
+// jobGroupMutation is the set of changes the mutator makes to a JobGroup.
+type jobGroupMutation struct {
+	metadata    map[string]interface{} // the object's metadata, nil if absent
+	original    map[string]interface{} // the spec as submitted
+	specData    map[string]interface{} // the spec with defaults applied
+	defaulted   map[string]interface{} // just the defaulted spec fields
+	labels      map[string]string      // labels to set
+	annotations map[string]string      // annotations to set
+	guard       bool                   // emit JSON patch "test" guards
+}
+
+// injectedMetadata returns the labels and annotations from inject that
+// metadata does not already carry, plus the defaults annotation when fields
+// were defaulted.
+func injectedMetadata(inject MetadataInjection, metadata, defaulted map[string]interface{}) (map[string]string, map[string]string) {
+	annotations := make(map[string]string, len(inject.Annotations)+1)
+	for key, value := range inject.Annotations {
+		annotations[key] = value
+	}
+	if inject.DefaultsAnnotation != "" && len(defaulted) > 0 {
+		annotations[inject.DefaultsAnnotation] = defaultedFields(defaulted)
+	}
+
+	return missingEntries(metadata, "labels", inject.Labels), missingEntries(metadata, "annotations", annotations)
+}
+
+// defaultedFields lists the defaulted spec fields, sorted and comma-separated.
+func defaultedFields(defaulted map[string]interface{}) string {
+	fields := make([]string, 0, len(defaulted))
+	for field := range defaulted {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, ",")
+}
+
+// missingEntries returns the entries of want that metadata[field] lacks or
+// holds with a different value.
+func missingEntries(metadata map[string]interface{}, field string, want map[string]string) map[string]string {
+	existing, _ := metadata[field].(map[string]interface{})
+
+	missing := make(map[string]string)
+	for key, value := range want {
+		if current, ok := existing[key].(string); !ok || current != value {
+			missing[key] = value
+		}
+	}
+	return missing
+}
+
+// buildPatch encodes m as a JSON patch. It replaces the whole spec and adds
+// metadata entries one by one, creating the label and annotation maps when
+// absent, each preceded by a "test" of the value it overwrites when m.guard
+// is set.
+func buildPatch(m jobGroupMutation) ([]byte, error) {
+	var ops []jsonPatchOp
+	if len(m.defaulted) > 0 {
+		if m.guard {
+			ops = append(ops, jsonPatchOp{Op: "test", Path: "/spec", Value: m.original})
+		}
+		ops = append(ops, jsonPatchOp{Op: "replace", Path: "/spec", Value: m.specData})
+	}
+	if m.metadata == nil && (len(m.labels) > 0 || len(m.annotations) > 0) {
+		ops = append(ops, jsonPatchOp{Op: "add", Path: "/metadata", Value: map[string]interface{}{}})
+	}
+	ops = append(ops, metadataOps(m.metadata, "labels", m.labels, m.guard)...)
+	ops = append(ops, metadataOps(m.metadata, "annotations", m.annotations, m.guard)...)
+
+	return json.Marshal(ops)
+}
+
+// jsonPatchOp is a single RFC 6902 operation.
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// metadataOps returns JSON patch operations setting entries in
+// /metadata/<field>, adding the map itself when metadata lacks it. With guard
+// set, each entry that replaces an existing value is tested first.
+func metadataOps(metadata map[string]interface{}, field string, entries map[string]string, guard bool) []jsonPatchOp {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	existing, ok := metadata[field].(map[string]interface{})
+	if !ok {
+		return []jsonPatchOp{{Op: "add", Path: "/metadata/" + field, Value: entries}}
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ops := make([]jsonPatchOp, 0, len(keys))
+	for _, key := range keys {
+		path := "/metadata/" + field + "/" + escapeJSONPointer(key)
+		if current, ok := existing[key]; ok && guard {
+			ops = append(ops, jsonPatchOp{Op: "test", Path: path, Value: current})
+		}
+		ops = append(ops, jsonPatchOp{Op: "add", Path: path, Value: entries[key]})
+	}
+	return ops
+}
+
+// escapeJSONPointer escapes a key for use as an RFC 6901 reference token.
+func escapeJSONPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -2,19 +2,40 @@ package webhook
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vjranagit/volcano/pkg/metrics"
 )
 
+// newJSONRequest builds a POST carrying body as an admission review would
+// arrive from the API server.
+func newJSONRequest(path string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
 func TestValidateJobGroup_Valid(t *testing.T) {
 	server := NewServer(8443, "", "", slog.Default())
 
@@ -43,7 +64,7 @@ func TestValidateJobGroup_Valid(t *testing.T) {
 		},
 	}
 
-	response := server.validateJobGroup(req)
+	response := server.validateJobGroup(server.logger, req)
 	assert.True(t, response.Allowed)
 	assert.Nil(t, response.Result)
 }
@@ -66,7 +87,7 @@ func TestValidateJobGroup_InvalidMinMember(t *testing.T) {
 		},
 	}
 
-	response := server.validateJobGroup(req)
+	response := server.validateJobGroup(server.logger, req)
 	assert.False(t, response.Allowed)
 	assert.Contains(t, response.Result.Message, "minMember must be positive")
 }
@@ -90,11 +111,317 @@ func TestValidateJobGroup_InvalidMaxMember(t *testing.T) {
 		},
 	}
 
-	response := server.validateJobGroup(req)
+	response := server.validateJobGroup(server.logger, req)
 	assert.False(t, response.Allowed)
 	assert.Contains(t, response.Result.Message, "maxMember must be >= minMember")
 }
 
+func TestValidateJobGroup_ReportsAllViolations(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	spec := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember":              -1,
+			"scheduleTimeoutSeconds": 0,
+			"priority":               5000,
+			"tasks": []interface{}{
+				map[string]interface{}{"name": "ps"},
+				map[string]interface{}{"name": "ps"},
+			},
+		},
+	}
+
+	raw, _ := json.Marshal(spec)
+	req := &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	}
+
+	response := server.validateJobGroup(server.logger, req)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "minMember must be positive; scheduleTimeoutSeconds must be positive; "+
+		`priority 5000 must be between 0 and 1000; duplicate task name "ps"`, response.Result.Message)
+
+	require.NotNil(t, response.Result.Details)
+	fields := make([]string, 0, len(response.Result.Details.Causes))
+	for _, cause := range response.Result.Details.Causes {
+		assert.Equal(t, metav1.CauseTypeFieldValueInvalid, cause.Type)
+		fields = append(fields, cause.Field)
+	}
+	assert.Equal(t, []string{
+		"spec.minMember",
+		"spec.scheduleTimeoutSeconds",
+		"spec.priority",
+		"spec.tasks[1].name",
+	}, fields)
+}
+
+func TestValidateJobGroup_Update(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	oldRaw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember":              4,
+			"queue":                  "research",
+			"priority":               10,
+			"scheduleTimeoutSeconds": 600,
+		},
+		"status": map[string]interface{}{
+			"running": 4,
+		},
+	})
+
+	newRequest := func(operation admissionv1.Operation, minMember int, queue string) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              minMember,
+				"queue":                  queue,
+				"priority":               20,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}
+	}
+
+	// Changing an unrelated field is allowed.
+	response := server.validateJobGroup(server.logger, newRequest(admissionv1.Update, 4, "research"))
+	assert.True(t, response.Allowed)
+
+	// Growing minMember is allowed.
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Update, 6, "research"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Update, 2, "research"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "minMember 2 cannot be lowered below the 4 running members", response.Result.Message)
+
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Update, 4, "production"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `spec.queue is immutable (was "research")`, response.Result.Message)
+
+	// Creates ignore the old object.
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Create, 2, "production"))
+	assert.True(t, response.Allowed)
+}
+
+func TestValidateJobGroup_ImmutableAnnotations(t *testing.T) {
+	server := NewServerWithOptions(WithImmutableAnnotations("scheduling.volcano.sh/owner"))
+
+	object := func(annotations map[string]interface{}) []byte {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":        "group",
+				"annotations": annotations,
+			},
+			"spec": map[string]interface{}{
+				"minMember":              2,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		return raw
+	}
+	oldRaw := object(map[string]interface{}{
+		"scheduling.volcano.sh/owner": "team-a",
+		"note":                        "first",
+	})
+
+	newRequest := func(operation admissionv1.Operation, annotations map[string]interface{}) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: object(annotations)},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}
+	}
+
+	// Changing an unprotected annotation is allowed.
+	response := server.validateJobGroup(server.logger, newRequest(admissionv1.Update, map[string]interface{}{
+		"scheduling.volcano.sh/owner": "team-a",
+		"note":                        "second",
+	}))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Update, map[string]interface{}{
+		"scheduling.volcano.sh/owner": "team-b",
+	}))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `annotation "scheduling.volcano.sh/owner" is immutable (was "team-a")`, response.Result.Message)
+	assert.Equal(t, "metadata.annotations[scheduling.volcano.sh/owner]", response.Result.Details.Causes[0].Field)
+
+	// Removing it is a change too.
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Update, nil))
+	assert.False(t, response.Allowed)
+
+	// Creates ignore the old object.
+	response = server.validateJobGroup(server.logger, newRequest(admissionv1.Create, map[string]interface{}{
+		"scheduling.volcano.sh/owner": "team-b",
+	}))
+	assert.True(t, response.Allowed)
+}
+
+func TestValidateJobGroup_MalformedVsMissing(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	tests := []struct {
+		name    string
+		raw     string
+		reason  metav1.StatusReason
+		code    int32
+		message string
+	}{
+		{
+			name:    "garbage bytes",
+			raw:     `{"spec": [`,
+			reason:  metav1.StatusReasonBadRequest,
+			code:    http.StatusBadRequest,
+			message: "malformed object, failed to unmarshal: ",
+		},
+		{
+			name:    "missing spec",
+			raw:     `{"metadata": {"name": "group"}}`,
+			reason:  metav1.StatusReasonInvalid,
+			code:    http.StatusUnprocessableEntity,
+			message: "spec field is required",
+		},
+		{
+			name:    "missing spec and name",
+			raw:     `{"metadata": {}}`,
+			reason:  metav1.StatusReasonInvalid,
+			code:    http.StatusUnprocessableEntity,
+			message: "spec field is required; metadata.name is required",
+		},
+		{
+			name:    "invalid spec",
+			raw:     `{"spec": {"minMember": 0, "scheduleTimeoutSeconds": 600}}`,
+			reason:  metav1.StatusReasonInvalid,
+			code:    http.StatusUnprocessableEntity,
+			message: "minMember must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := server.validateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+				UID:    "test-uid",
+				Object: runtime.RawExtension{Raw: []byte(tt.raw)},
+			})
+
+			assert.False(t, response.Allowed)
+			assert.Equal(t, tt.reason, response.Result.Reason)
+			assert.Equal(t, tt.code, response.Result.Code)
+			assert.Contains(t, response.Result.Message, tt.message)
+		})
+	}
+}
+
+func TestValidateJobGroup_ScheduleTimeoutRange(t *testing.T) {
+	newRequest := func(timeout int) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              1,
+				"scheduleTimeoutSeconds": timeout,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	server := NewServer(8443, "", "", slog.Default())
+
+	response := server.validateJobGroup(server.logger, newRequest(1))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "scheduleTimeoutSeconds 1 is below the minimum of 30", response.Result.Message)
+
+	response = server.validateJobGroup(server.logger, newRequest(30))
+	assert.True(t, response.Allowed)
+
+	// Unbounded above by default.
+	response = server.validateJobGroup(server.logger, newRequest(86400))
+	assert.True(t, response.Allowed)
+
+	server = NewServerWithOptions(WithScheduleTimeoutRange(60, 3600))
+
+	response = server.validateJobGroup(server.logger, newRequest(30))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "scheduleTimeoutSeconds 30 is below the minimum of 60", response.Result.Message)
+
+	response = server.validateJobGroup(server.logger, newRequest(7200))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "scheduleTimeoutSeconds 7200 is above the maximum of 3600", response.Result.Message)
+}
+
+func TestValidateJobGroup_ClusterMaxMinMember(t *testing.T) {
+	newRequest := func(minMember int) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              minMember,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	// Uncapped by default.
+	server := NewServer(8443, "", "", slog.Default())
+	response := server.validateJobGroup(server.logger, newRequest(10000))
+	assert.True(t, response.Allowed)
+
+	server = NewServerWithOptions(WithClusterMaxMinMember(500))
+
+	response = server.validateJobGroup(server.logger, newRequest(500))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(501))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "minMember 501 is above the cluster-wide maximum of 500", response.Result.Message)
+	assert.Equal(t, "spec.minMember", response.Result.Details.Causes[0].Field)
+}
+
+func TestValidateJobGroup_MaxTasks(t *testing.T) {
+	newRequest := func(count int) *admissionv1.AdmissionRequest {
+		tasks := make([]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			tasks = append(tasks, map[string]interface{}{"name": fmt.Sprintf("task-%d", i), "replicas": 1})
+		}
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              1,
+				"scheduleTimeoutSeconds": 600,
+				"tasks":                  tasks,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	// Uncapped by default.
+	server := NewServer(8443, "", "", slog.Default())
+	response := server.validateJobGroup(server.logger, newRequest(20))
+	assert.True(t, response.Allowed)
+
+	server = NewServerWithOptions(WithMaxTasks(3))
+
+	response = server.validateJobGroup(server.logger, newRequest(3))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(4))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "4 tasks exceed the maximum of 3", response.Result.Message)
+	assert.Equal(t, "spec.tasks", response.Result.Details.Causes[0].Field)
+}
+
 func TestMutateJobGroup_AppliesDefaults(t *testing.T) {
 	server := NewServer(8443, "", "", slog.Default())
 
@@ -112,7 +439,7 @@ func TestMutateJobGroup_AppliesDefaults(t *testing.T) {
 		},
 	}
 
-	response := server.mutateJobGroup(req)
+	response := server.mutateJobGroup(server.logger, req)
 	assert.True(t, response.Allowed)
 	assert.NotNil(t, response.Patch)
 	assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
@@ -154,9 +481,1400 @@ func TestParseAdmissionReview_Valid(t *testing.T) {
 	}
 
 	body, _ := json.Marshal(review)
-	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req := newJSONRequest("/validate", body)
+
+	parsed, err := server.parseAdmissionReview(req)
+	require.NoError(t, err)
+	assert.Equal(t, "test-uid", string(parsed.Request.UID))
+}
+
+func TestParseAdmissionReview_Gzip(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	body, _ := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+		},
+	})
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write(body)
+	require.NoError(t, gz.Close())
 
+	req := newJSONRequest("/validate", compressed.Bytes())
+	req.Header.Set("Content-Encoding", "gzip")
 	parsed, err := server.parseAdmissionReview(req)
 	require.NoError(t, err)
 	assert.Equal(t, "test-uid", string(parsed.Request.UID))
+
+	// A body that is not gzip is reported as such.
+	req = newJSONRequest("/validate", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = server.parseAdmissionReview(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "corrupt gzip body")
+
+	// Truncated gzip fails while reading.
+	req = newJSONRequest("/validate", compressed.Bytes()[:compressed.Len()/2])
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = server.parseAdmissionReview(req)
+	require.Error(t, err)
+
+	req = newJSONRequest("/validate", body)
+	req.Header.Set("Content-Encoding", "br")
+	_, err = server.parseAdmissionReview(req)
+	var reqErr *requestError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, http.StatusUnsupportedMediaType, reqErr.code)
+}
+
+func TestValidateJobGroup_EmptyObject(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Operation: admissionv1.Update,
+	}
+
+	response := server.validateJobGroup(server.logger, req)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "no object to validate (operation UPDATE)", response.Result.Message)
+
+	// A DELETE carries the object being deleted in the old object only.
+	req = &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Operation: admissionv1.Delete,
+		OldObject: runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"group"},"spec":{"minMember":2,"scheduleTimeoutSeconds":600}}`)},
+	}
+	response = server.validateJobGroup(server.logger, req)
+	assert.True(t, response.Allowed)
+}
+
+func TestNewServerWithOptions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	defaults := MutationDefaults{MaxMemberFactor: 3, Priority: 10, ScheduleTimeoutSeconds: 120}
+	filter := NamespaceFilter{Exclude: []string{"kube-system"}}
+
+	server := NewServerWithOptions(
+		WithPort(9443),
+		WithTLS("tls.crt", "tls.key"),
+		WithLogger(logger),
+		WithMutationDefaults(defaults),
+		WithNamespaceFilter(filter),
+	)
+
+	assert.Equal(t, 9443, server.port)
+	assert.Equal(t, "tls.crt", server.certFile)
+	assert.Equal(t, "tls.key", server.keyFile)
+	assert.Same(t, logger, server.logger)
+	assert.Equal(t, defaults, server.config.Defaults)
+	assert.Equal(t, filter, server.config.Namespaces)
+}
+
+func TestNewServer_UsesDefaultConfig(t *testing.T) {
+	server := NewServer(8443, "", "", nil)
+
+	assert.NotNil(t, server.logger)
+	assert.Equal(t, DefaultConfig(), server.config)
+}
+
+func TestMutateJobGroup_ExcludedNamespace(t *testing.T) {
+	server := NewServerWithOptions(WithNamespaceFilter(NamespaceFilter{Exclude: []string{"kube-system"}}))
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember": 3,
+		},
+	})
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "kube-system",
+		Object: runtime.RawExtension{
+			Raw: raw,
+		},
+	}
+
+	response := server.mutateJobGroup(server.logger, req)
+	assert.True(t, response.Allowed)
+	assert.Nil(t, response.Patch)
+}
+
+func TestValidateJobGroup_NamespacePattern(t *testing.T) {
+	server := NewServerWithOptions(WithNamespaceFilter(NamespaceFilter{Exclude: []string{"team-*"}}))
+
+	// minMember is missing, which is enforced only outside team namespaces.
+	raw, _ := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{}})
+	validate := func(namespace string) bool {
+		return server.validateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Namespace: namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}).Allowed
+	}
+
+	assert.True(t, validate("team-a"))
+	assert.True(t, validate("team-b"))
+	assert.False(t, validate("prod"))
+}
+
+func TestHandleValidate_LogsCarryUID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server := NewServerWithOptions(WithLogger(logger))
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember":              3,
+			"scheduleTimeoutSeconds": 600,
+		},
+	})
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Name:      "test-group",
+			Namespace: "default",
+			Object: runtime.RawExtension{
+				Raw: raw,
+			},
+		},
+	}
+
+	body, _ := json.Marshal(review)
+	req := newJSONRequest("/validate", body)
+	rec := httptest.NewRecorder()
+
+	server.handleValidate(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &entry))
+		assert.Equal(t, "test-uid", entry["uid"])
+		assert.Equal(t, "default", entry["namespace"])
+	}
+}
+
+func TestHandleValidate_ParseErrorCounted(t *testing.T) {
+	server := NewServerWithOptions(WithCollector(metrics.NewCollector(slog.Default())))
+
+	req := newJSONRequest("/validate", []byte("not json"))
+	rec := httptest.NewRecorder()
+
+	server.handleValidate(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandlers_ParseErrorDeniesInReview(t *testing.T) {
+	server := NewServerWithOptions(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	for _, path := range []string{"/validate", "/mutate"} {
+		for name, body := range map[string]string{
+			"bad field type": `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "bad-uid", "operation": 5}}`,
+			"bad kind":       `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview", "request": {"uid": "bad-uid", "kind": "JobGroup"}}`,
+		} {
+			t.Run(path+" "+name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				server.routes().ServeHTTP(rec, newJSONRequest(path, []byte(body)))
+
+				require.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+				var review admissionv1.AdmissionReview
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+				assert.Equal(t, "admission.k8s.io/v1", review.APIVersion)
+				assert.Equal(t, "AdmissionReview", review.Kind)
+				require.NotNil(t, review.Response)
+				assert.Equal(t, types.UID("bad-uid"), review.Response.UID)
+				assert.False(t, review.Response.Allowed)
+				assert.Equal(t, metav1.StatusReasonBadRequest, review.Response.Result.Reason)
+				assert.NotEmpty(t, review.Response.Result.Message)
+			})
+		}
+
+		// Without a UID the API server cannot match a review to the
+		// request, so a plain 400 is returned.
+		for name, body := range map[string]string{
+			"garbage":    "not json",
+			"no request": `{"apiVersion": "admission.k8s.io/v1", "kind": "AdmissionReview"}`,
+		} {
+			t.Run(path+" "+name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				server.routes().ServeHTTP(rec, newJSONRequest(path, []byte(body)))
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		}
+	}
+
+	// Protocol errors still get a plain HTTP status.
+	rec := httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/validate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestValidateJobGroup_PriorityRange(t *testing.T) {
+	server := NewServerWithOptions(WithPriorityRange(0, 100))
+
+	tests := []struct {
+		name     string
+		priority int
+		allowed  bool
+	}{
+		{name: "in range", priority: 100, allowed: true},
+		{name: "above max", priority: 2000000000, allowed: false},
+		{name: "below min", priority: -1, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, _ := json.Marshal(map[string]interface{}{
+				"spec": map[string]interface{}{
+					"minMember":              3,
+					"scheduleTimeoutSeconds": 600,
+					"priority":               tt.priority,
+				},
+			})
+			req := &admissionv1.AdmissionRequest{
+				UID: "test-uid",
+				Object: runtime.RawExtension{
+					Raw: raw,
+				},
+			}
+
+			response := server.validateJobGroup(server.logger, req)
+			assert.Equal(t, tt.allowed, response.Allowed)
+			if !tt.allowed {
+				assert.Contains(t, response.Result.Message, "must be between 0 and 100")
+			}
+		})
+	}
+}
+
+func TestMutateJobGroup_PatchApplies(t *testing.T) {
+	original := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "test-group"},
+		"spec": map[string]interface{}{
+			"minMember": 3,
+		},
+	}
+	raw, _ := json.Marshal(original)
+
+	server := NewServer(8443, "", "", slog.Default())
+	response := server.mutateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	})
+	require.NotNil(t, response.PatchType)
+	assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
+
+	var ops []struct {
+		Op    string                 `json:"op"`
+		Path  string                 `json:"path"`
+		Value map[string]interface{} `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(response.Patch, &ops))
+	require.Len(t, ops, 1)
+	require.Equal(t, "replace", ops[0].Op)
+	require.Equal(t, "/spec", ops[0].Path)
+
+	spec := ops[0].Value
+	assert.Equal(t, 3.0, spec["minMember"])
+	assert.Equal(t, 6.0, spec["maxMember"])
+	assert.Equal(t, 50.0, spec["priority"])
+	assert.Equal(t, 600.0, spec["scheduleTimeoutSeconds"])
+}
+
+func TestHandleValidate_AuditsDecisions(t *testing.T) {
+	var buf bytes.Buffer
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithResponseCache(10, time.Minute),
+	)
+
+	// The second request for minMember 0 is a retry, answered from the
+	// response cache.
+	for _, minMember := range []int{3, 0, 0} {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              minMember,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		review := &admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "admission.k8s.io/v1",
+				Kind:       "AdmissionReview",
+			},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       types.UID(fmt.Sprintf("uid-%d", minMember)),
+				Name:      "test-group",
+				Namespace: "default",
+				Operation: admissionv1.Create,
+				Object: runtime.RawExtension{
+					Raw: raw,
+				},
+			},
+		}
+
+		body, _ := json.Marshal(review)
+		req := newJSONRequest("/validate", body)
+		server.handleValidate(httptest.NewRecorder(), req)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	var allowed, denied, retried map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &allowed))
+	require.NoError(t, json.Unmarshal(lines[1], &denied))
+	require.NoError(t, json.Unmarshal(lines[2], &retried))
+
+	assert.Equal(t, true, allowed["allowed"])
+	assert.Equal(t, "CREATE", allowed["operation"])
+	assert.NotContains(t, allowed, "reason")
+
+	assert.Equal(t, false, denied["allowed"])
+	assert.Equal(t, "uid-0", denied["uid"])
+	assert.Equal(t, "minMember must be positive", denied["reason"])
+	assert.NotContains(t, denied, "cached")
+
+	assert.Equal(t, false, retried["allowed"])
+	assert.Equal(t, "uid-0", retried["uid"])
+	assert.Equal(t, "minMember must be positive", retried["reason"])
+	assert.Equal(t, true, retried["cached"])
+}
+
+func TestHandleValidate_ContentNegotiation(t *testing.T) {
+	server := NewServerWithOptions(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+		},
+	}
+	body, _ := json.Marshal(review)
+
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		code        int
+	}{
+		{name: "json", contentType: "application/json", code: http.StatusOK},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", accept: "application/json, */*", code: http.StatusOK},
+		{name: "plain text", contentType: "text/plain", code: http.StatusUnsupportedMediaType},
+		{name: "missing content type", code: http.StatusUnsupportedMediaType},
+		{name: "yaml only", contentType: "application/json", accept: "application/yaml", code: http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			server.handleValidate(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestHandleValidate_PrettyResponse(t *testing.T) {
+	server := NewServerWithOptions(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+		},
+	}
+	body, _ := json.Marshal(review)
+
+	rec := httptest.NewRecorder()
+	server.handleValidate(rec, newJSONRequest("/validate?pretty=true", body))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "\n  \"")
+}
+
+func TestValidateJobGroup_MaxContainerRequests(t *testing.T) {
+	server := NewServerWithOptions(WithMaxContainerRequests(corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("64"),
+		corev1.ResourceMemory: resource.MustParse("256Gi"),
+	}))
+
+	newRequest := func(cpu, memory string) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              2,
+				"scheduleTimeoutSeconds": 600,
+				"tasks": []interface{}{
+					map[string]interface{}{
+						"name":     "worker",
+						"replicas": 2,
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{
+										"name": "main",
+										"resources": map[string]interface{}{
+											"requests": map[string]interface{}{
+												"cpu":    cpu,
+												"memory": memory,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+			Object: runtime.RawExtension{
+				Raw: raw,
+			},
+		}
+	}
+
+	response := server.validateJobGroup(server.logger, newRequest("8", "32Gi"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest("8", "512Gi"))
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, `task "worker" container "main" requests memory=512Gi`)
+
+	// The check is disabled by default.
+	response = NewServer(8443, "", "", nil).validateJobGroup(server.logger, newRequest("1000", "512Gi"))
+	assert.True(t, response.Allowed)
+}
+
+func TestValidateJobGroup_AllowedResources(t *testing.T) {
+	server := NewServerWithOptions(WithAllowedResources(
+		corev1.ResourceCPU, corev1.ResourceMemory, "nvidia.com/gpu"))
+
+	newRequest := func(requests map[string]interface{}) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              1,
+				"scheduleTimeoutSeconds": 600,
+				"tasks": []interface{}{
+					map[string]interface{}{
+						"name": "worker",
+						"template": map[string]interface{}{
+							"spec": map[string]interface{}{
+								"containers": []interface{}{
+									map[string]interface{}{
+										"name":      "main",
+										"resources": map[string]interface{}{"requests": requests},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	response := server.validateJobGroup(server.logger, newRequest(map[string]interface{}{
+		"cpu": "4", "memory": "8Gi", "nvidia.com/gpu": "1",
+	}))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(map[string]interface{}{
+		"cpu": "4", "example.com/fpga": "1",
+	}))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `task "worker" container "main" requests resource "example.com/fpga", which is not allowed`,
+		response.Result.Message)
+
+	// Any resource is allowed by default.
+	response = NewServer(8443, "", "", nil).validateJobGroup(server.logger, newRequest(map[string]interface{}{
+		"example.com/fpga": "1",
+	}))
+	assert.True(t, response.Allowed)
+}
+
+// histogramSample returns the sample count and sum of the unlabeled histogram
+// name gathered from collector.
+func histogramSample(t *testing.T, collector *metrics.Collector, name string) (uint64, float64) {
+	t.Helper()
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			histogram := family.GetMetric()[0].GetHistogram()
+			return histogram.GetSampleCount(), histogram.GetSampleSum()
+		}
+	}
+	return 0, 0
+}
+
+func TestMutateJobGroup_ObservesPatchSize(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default())
+	server := NewServerWithOptions(WithCollector(collector))
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember": 3,
+		},
+	})
+	req := &admissionv1.AdmissionRequest{
+		UID: "test-uid",
+		Object: runtime.RawExtension{
+			Raw: raw,
+		},
+	}
+
+	countBefore, sumBefore := histogramSample(t, collector, "volcano_webhook_patch_bytes")
+
+	response := server.mutateJobGroup(server.logger, req)
+	require.NotEmpty(t, response.Patch)
+
+	count, sum := histogramSample(t, collector, "volcano_webhook_patch_bytes")
+	assert.Equal(t, countBefore+1, count)
+	assert.Equal(t, sumBefore+float64(len(response.Patch)), sum)
+}
+
+func TestHandlers_Draining(t *testing.T) {
+	server := NewServerWithOptions(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+		},
+	}
+	body, _ := json.Marshal(review)
+
+	rec := httptest.NewRecorder()
+	server.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.handleValidate(rec, newJSONRequest("/validate", body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	server.draining.Store(true)
+
+	rec = httptest.NewRecorder()
+	server.handleValidate(rec, newJSONRequest("/validate", body))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.handleMutate(rec, newJSONRequest("/mutate", body))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Liveness is unaffected by draining.
+	rec = httptest.NewRecorder()
+	server.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRun_DrainDelay(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSignedCert([]string{"localhost"})
+	require.NoError(t, err)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithPort(0),
+		WithCertificate(cert),
+		WithDrainDelay(300*time.Millisecond),
+		WithShutdownTimeout(time.Second),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+	cancel()
+
+	// Readiness fails while the server keeps listening out the delay.
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		server.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+	assert.Eventually(t, func() bool { return ready() == http.StatusServiceUnavailable },
+		time.Second, 5*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned during the drain delay: %v", err)
+	default:
+	}
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the drain delay")
+	}
+}
+
+func TestValidateJobGroup_Schema(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"spec": {"type": "object", "required": ["minMember", "costCenter"]}
+		}
+	}`))
+	require.NoError(t, err)
+	server := NewServerWithOptions(WithSchema(schema))
+
+	newRequest := func(spec map[string]interface{}) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{"spec": spec})
+		return &admissionv1.AdmissionRequest{
+			UID: "test-uid",
+			Object: runtime.RawExtension{
+				Raw: raw,
+			},
+		}
+	}
+
+	response := server.validateJobGroup(server.logger, newRequest(map[string]interface{}{"minMember": 3}))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "schema validation failed: spec.costCenter: is required", response.Result.Message)
+
+	// The schema replaces the built-in checks, which would reject the
+	// missing scheduleTimeoutSeconds.
+	response = server.validateJobGroup(server.logger, newRequest(map[string]interface{}{"minMember": 3, "costCenter": "cc-42"}))
+	assert.True(t, response.Allowed)
+}
+
+func TestHandleReload(t *testing.T) {
+	path := writeConfig(t, "defaults:\n  maxMemberFactor: 2\n  priority: 50\n  scheduleTimeoutSeconds: 600\n")
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithConfig(cfg),
+		WithConfigReload(path, "secret"),
+	)
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"minMember": 3,
+		},
+	})
+	mutate := func() map[string]interface{} {
+		response := server.mutateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		})
+		var ops []struct {
+			Value map[string]interface{} `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(response.Patch, &ops))
+		return ops[0].Value
+	}
+	reload := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		server.handleReload(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, 50.0, mutate()["priority"])
+
+	require.NoError(t, os.WriteFile(path, []byte("defaults:\n  maxMemberFactor: 4\n  priority: 70\n  scheduleTimeoutSeconds: 300\n"), 0o600))
+	assert.Equal(t, http.StatusUnauthorized, reload("wrong"))
+	assert.Equal(t, 50.0, mutate()["priority"])
+
+	assert.Equal(t, http.StatusOK, reload("secret"))
+	spec := mutate()
+	assert.Equal(t, 70.0, spec["priority"])
+	assert.Equal(t, 12.0, spec["maxMember"])
+
+	// An invalid config is rejected and the previous one kept.
+	require.NoError(t, os.WriteFile(path, []byte("minPriority: 10\nmaxPriority: 1\n"), 0o600))
+	assert.Equal(t, http.StatusUnprocessableEntity, reload("secret"))
+	assert.Equal(t, 70.0, mutate()["priority"])
+}
+
+func TestHandleReload_KeepsOptions(t *testing.T) {
+	path := writeConfig(t, "defaults:\n  maxMemberFactor: 2\n  priority: 50\n  scheduleTimeoutSeconds: 600\n")
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	schema, err := ParseSchema([]byte(testSchema))
+	require.NoError(t, err)
+
+	// Options before WithConfig are replaced by it; those after it stay in
+	// force across reloads, as cmd/webhook applies --schema-file.
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithMaxTasks(5),
+		WithConfig(cfg),
+		WithConfigReload(path, "secret"),
+		WithSchema(schema),
+		WithRequiredLabels("team"),
+	)
+	assert.Zero(t, server.currentConfig().MaxTasks)
+
+	require.NoError(t, os.WriteFile(path, []byte("defaults:\n  maxMemberFactor: 4\n  priority: 70\n  scheduleTimeoutSeconds: 300\n"), 0o600))
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	server.handleReload(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	reloaded := server.currentConfig()
+	assert.Equal(t, 70, reloaded.Defaults.Priority)
+	assert.Same(t, schema, reloaded.Schema)
+	assert.Equal(t, []string{"team"}, reloaded.RequiredLabels)
+	assert.Zero(t, reloaded.MaxTasks)
+
+	raw, _ := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"minMember": 3}})
+	response := server.validateJobGroup(server.logger, &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	})
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, "schema validation failed")
+}
+
+func TestHandleReload_Disabled(t *testing.T) {
+	server := NewServer(8443, "", "", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	rec := httptest.NewRecorder()
+	server.handleReload(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestValidateJobGroup_DuplicateTaskNames(t *testing.T) {
+	server := NewServer(8443, "", "", nil)
+
+	newRequest := func(names ...string) *admissionv1.AdmissionRequest {
+		tasks := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			tasks = append(tasks, map[string]interface{}{"name": name, "replicas": 1})
+		}
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              2,
+				"scheduleTimeoutSeconds": 600,
+				"tasks":                  tasks,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	response := server.validateJobGroup(server.logger, newRequest("ps", "worker", "ps"))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, `duplicate task name "ps"`, response.Result.Message)
+
+	response = server.validateJobGroup(server.logger, newRequest("ps", "worker"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest())
+	assert.True(t, response.Allowed)
+}
+
+func TestValidateJobGroup_StrictTaskReplicas(t *testing.T) {
+	newRequest := func(maxMember int, replicas ...int) *admissionv1.AdmissionRequest {
+		tasks := make([]interface{}, 0, len(replicas))
+		for i, n := range replicas {
+			tasks = append(tasks, map[string]interface{}{"name": fmt.Sprintf("task-%d", i), "replicas": n})
+		}
+		spec := map[string]interface{}{
+			"minMember":              2,
+			"scheduleTimeoutSeconds": 600,
+			"tasks":                  tasks,
+		}
+		if maxMember > 0 {
+			spec["maxMember"] = maxMember
+		}
+		raw, _ := json.Marshal(map[string]interface{}{"spec": spec})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	// maxMember is only an upper bound by default.
+	server := NewServer(8443, "", "", nil)
+	response := server.validateJobGroup(server.logger, newRequest(8, 1, 4))
+	assert.True(t, response.Allowed)
+
+	server = NewServerWithOptions(WithStrictTaskReplicas(true))
+
+	response = server.validateJobGroup(server.logger, newRequest(8, 1, 4))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "task replicas sum to 5, but maxMember is 8", response.Result.Message)
+	assert.Equal(t, "spec.tasks", response.Result.Details.Causes[0].Field)
+
+	response = server.validateJobGroup(server.logger, newRequest(5, 1, 4))
+	assert.True(t, response.Allowed)
+
+	// Without a maxMember there is nothing to compare against.
+	response = server.validateJobGroup(server.logger, newRequest(0, 1, 4))
+	assert.True(t, response.Allowed)
+}
+
+func TestHandleHealth_CertUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0o600))
+
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithTLS(certFile, keyFile),
+		WithCertGracePeriod(time.Minute),
+	)
+	now := time.Now()
+	server.now = func() time.Time { return now }
+
+	health := func() int {
+		rec := httptest.NewRecorder()
+		server.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, health())
+
+	require.NoError(t, os.Remove(certFile))
+	assert.Equal(t, http.StatusOK, health()) // within the grace period
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, http.StatusInternalServerError, health())
+
+	// Recovering the file resets the grace window.
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0o600))
+	assert.Equal(t, http.StatusOK, health())
+}
+
+func TestHandleValidate_CachesDecisionsByUID(t *testing.T) {
+	now := time.Now()
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithResponseCache(10, time.Minute),
+	)
+	server.now = func() time.Time { return now }
+
+	validate := func(uid string) *admissionv1.AdmissionResponse {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"minMember":              3,
+				"priority":               500,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		body, _ := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "admission.k8s.io/v1",
+				Kind:       "AdmissionReview",
+			},
+			Request: &admissionv1.AdmissionRequest{
+				UID:    types.UID(uid),
+				Object: runtime.RawExtension{Raw: raw},
+			},
+		})
+
+		rec := httptest.NewRecorder()
+		server.handleValidate(rec, newJSONRequest("/validate", body))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var review admissionv1.AdmissionReview
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+		return review.Response
+	}
+
+	assert.True(t, validate("retried").Allowed)
+
+	// Tighten the policy: a recomputed decision would now deny.
+	server.configMu.Lock()
+	server.config.MaxPriority = 100
+	server.configMu.Unlock()
+
+	response := validate("retried")
+	assert.True(t, response.Allowed, "retry should be served from the cache")
+	assert.Equal(t, types.UID("retried"), response.UID)
+	assert.False(t, validate("fresh").Allowed)
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, validate("retried").Allowed, "expired decision should be recomputed")
+}
+
+func TestHandleMutate_ReinvocationRecomputesPatch(t *testing.T) {
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithResponseCache(10, time.Minute),
+	)
+
+	// A reinvoked mutating webhook gets the same UID with the object other
+	// webhooks have changed since.
+	mutate := func(minMember int) map[string]interface{} {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{"minMember": minMember},
+		})
+		body, _ := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "admission.k8s.io/v1",
+				Kind:       "AdmissionReview",
+			},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "reinvoked",
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+
+		rec := httptest.NewRecorder()
+		server.handleMutate(rec, newJSONRequest("/mutate", body))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var review admissionv1.AdmissionReview
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+		var ops []struct {
+			Value map[string]interface{} `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(review.Response.Patch, &ops))
+		require.Len(t, ops, 1)
+		return ops[0].Value
+	}
+
+	assert.Equal(t, 3.0, mutate(3)["minMember"])
+	assert.Equal(t, 5.0, mutate(5)["minMember"], "patch should be recomputed for the changed object")
+}
+
+func TestResponseCache_EvictsOldest(t *testing.T) {
+	now := time.Now()
+	cache := newResponseCache(2, time.Minute)
+
+	for _, uid := range []string{"a", "b", "c"} {
+		cache.put(uid, &admissionv1.AdmissionResponse{UID: types.UID(uid), Allowed: true}, now)
+	}
+
+	_, ok := cache.get("a", now)
+	assert.False(t, ok)
+	response, ok := cache.get("c", now)
+	require.True(t, ok)
+	assert.Equal(t, types.UID("c"), response.UID)
+
+	assert.Nil(t, newResponseCache(0, time.Minute))
+	_, ok = (*responseCache)(nil).get("a", now)
+	assert.False(t, ok)
+}
+
+func TestHandleValidate_ConcurrencyLimit(t *testing.T) {
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithMaxConcurrentRequests(2),
+	)
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"minMember": 1, "scheduleTimeoutSeconds": 600},
+	})
+	body, _ := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		},
+	})
+
+	validate := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.handleValidate(rec, newJSONRequest("/validate", body))
+		return rec
+	}
+
+	// Hold both slots as two in-flight requests would.
+	releaseFirst, ok := server.acquireSlot(httptest.NewRecorder())
+	require.True(t, ok)
+	releaseSecond, ok := server.acquireSlot(httptest.NewRecorder())
+	require.True(t, ok)
+
+	rec := validate()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	releaseFirst()
+	assert.Equal(t, http.StatusOK, validate().Code)
+	assert.Len(t, server.slots, 1, "finished requests must free their slot")
+
+	releaseSecond()
+	assert.Empty(t, server.slots)
+}
+
+func TestHandleValidate_InflightGauge(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default())
+	server := NewServerWithOptions(
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithCollector(collector),
+	)
+
+	inflight := func() float64 {
+		families, err := collector.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "volcano_webhook_inflight_requests" {
+				return family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		return 0
+	}
+	before := inflight()
+
+	// The handler blocks reading the body until the pipe is closed.
+	body, writer := io.Pipe()
+	req := httptest.NewRequest(http.MethodPost, "/validate", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.handleValidate(httptest.NewRecorder(), req)
+	}()
+
+	assert.Eventually(t, func() bool { return inflight() == before+1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, writer.Close())
+	<-done
+	assert.Equal(t, before, inflight())
+}
+
+func TestMutateJobGroup_InjectsMetadata(t *testing.T) {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "test-group",
+			"labels": map[string]interface{}{"team": "ml", "tier": "batch"},
+		},
+		"spec": map[string]interface{}{
+			"minMember":              3,
+			"maxMember":              6,
+			"priority":               1,
+			"scheduleTimeoutSeconds": 300,
+		},
+	})
+
+	server := NewServerWithOptions(WithMetadataInjection(MetadataInjection{
+		Labels:             map[string]string{"tier": "batch", "volcano.sh/gang": "true"},
+		Annotations:        map[string]string{"volcano.sh/hint": "spread"},
+		DefaultsAnnotation: "volcano.sh/defaulted",
+	}))
+	req := &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	}
+
+	response := server.mutateJobGroup(server.logger, req)
+	require.NotNil(t, response.PatchType)
+	assert.Equal(t, admissionv1.PatchTypeJSONPatch, *response.PatchType)
+
+	var ops []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(response.Patch, &ops))
+
+	// Nothing was defaulted, so the spec is left alone and no defaults
+	// annotation is added; the existing label is kept as is.
+	require.Len(t, ops, 2)
+	assert.Equal(t, "add", ops[0].Op)
+	assert.Equal(t, "/metadata/labels/volcano.sh~1gang", ops[0].Path)
+	assert.Equal(t, "true", ops[0].Value)
+	assert.Equal(t, "add", ops[1].Op)
+	assert.Equal(t, "/metadata/annotations", ops[1].Path)
+	assert.Equal(t, map[string]interface{}{"volcano.sh/hint": "spread"}, ops[1].Value)
+}
+
+func TestMutateJobGroup_DefaultsAnnotation(t *testing.T) {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "test-group"},
+		"spec": map[string]interface{}{
+			"minMember":              3,
+			"scheduleTimeoutSeconds": 300,
+		},
+	})
+
+	server := NewServerWithOptions(
+		WithMetadataInjection(MetadataInjection{DefaultsAnnotation: "volcano.sh/defaulted"}),
+	)
+	req := &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	}
+
+	response := server.mutateJobGroup(server.logger, req)
+	require.NotNil(t, response.PatchType)
+
+	var ops []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(response.Patch, &ops))
+	require.Len(t, ops, 2)
+	assert.Equal(t, "/spec", ops[0].Path)
+	assert.Contains(t, ops[0].Value, "maxMember")
+	assert.Equal(t, "add", ops[1].Op)
+	assert.Equal(t, "/metadata/annotations", ops[1].Path)
+	assert.Equal(t, map[string]interface{}{"volcano.sh/defaulted": "maxMember,priority"}, ops[1].Value)
+}
+
+func TestMutateJobGroup_AuditAnnotations(t *testing.T) {
+	newReq := func(spec map[string]interface{}) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{"spec": spec})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+	server := NewServerWithOptions()
+
+	response := server.mutateJobGroup(server.logger, newReq(map[string]interface{}{
+		"minMember":              3,
+		"scheduleTimeoutSeconds": 300,
+	}))
+	assert.Equal(t, map[string]string{DefaultedFieldsAuditAnnotation: "maxMember,priority"}, response.AuditAnnotations)
+
+	// Nothing defaulted, nothing to audit.
+	response = server.mutateJobGroup(server.logger, newReq(map[string]interface{}{
+		"minMember":              3,
+		"maxMember":              6,
+		"priority":               10,
+		"scheduleTimeoutSeconds": 300,
+	}))
+	assert.Nil(t, response.AuditAnnotations)
+}
+
+func TestDebugServer(t *testing.T) {
+	server := NewServerWithOptions()
+	assert.Nil(t, server.newDebugServer())
+
+	server = NewServerWithOptions(WithDebugPort(6060))
+	debug := server.newDebugServer()
+	require.NotNil(t, debug)
+	assert.Equal(t, "127.0.0.1:6060", debug.Addr)
+
+	rec := httptest.NewRecorder()
+	debug.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// pprof is never served on the admission port.
+	rec = httptest.NewRecorder()
+	server.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMutateJobGroup_PatchTestGuards(t *testing.T) {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "test-group",
+			"labels": map[string]interface{}{"tier": "online"},
+		},
+		"spec": map[string]interface{}{"minMember": 3},
+	})
+	req := &admissionv1.AdmissionRequest{
+		UID:    "test-uid",
+		Object: runtime.RawExtension{Raw: raw},
+	}
+
+	type op struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	patchOps := func(guard bool) []op {
+		server := NewServerWithOptions(
+			WithPatchTestGuards(guard),
+			WithMetadataInjection(MetadataInjection{Labels: map[string]string{"tier": "batch", "team": "ml"}}),
+		)
+		response := server.mutateJobGroup(server.logger, req)
+		var ops []op
+		require.NoError(t, json.Unmarshal(response.Patch, &ops))
+		return ops
+	}
+
+	guarded := patchOps(true)
+	require.Len(t, guarded, 5)
+	assert.Equal(t, op{Op: "test", Path: "/spec", Value: map[string]interface{}{"minMember": float64(3)}}, guarded[0])
+	assert.Equal(t, "replace", guarded[1].Op)
+	assert.Equal(t, op{Op: "add", Path: "/metadata/labels/team", Value: "ml"}, guarded[2])
+	assert.Equal(t, op{Op: "test", Path: "/metadata/labels/tier", Value: "online"}, guarded[3])
+	assert.Equal(t, op{Op: "add", Path: "/metadata/labels/tier", Value: "batch"}, guarded[4])
+
+	for _, o := range patchOps(false) {
+		assert.NotEqual(t, "test", o.Op)
+	}
+}
+
+func TestValidateJobGroup_DNSNames(t *testing.T) {
+	server := NewServer(8443, "", "", slog.Default())
+
+	newRequest := func(name, namespace string) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"minMember":              2,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:       "test-uid",
+			Namespace: namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	response := server.validateJobGroup(server.logger, newRequest("train.v2-group", "ml-team"))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest("train_group", "default"))
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, `metadata.name "train_group" is invalid: a lowercase RFC 1123 subdomain`)
+	assert.Equal(t, "metadata.name", response.Result.Details.Causes[0].Field)
+
+	response = server.validateJobGroup(server.logger, newRequest("train", "ML.Team"))
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Result.Message, `metadata.namespace "ML.Team" is invalid: a lowercase RFC 1123 label`)
+}
+
+func TestValidateJobGroup_RequiredLabels(t *testing.T) {
+	newRequest := func(labels map[string]interface{}) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "group", "labels": labels},
+			"spec": map[string]interface{}{
+				"minMember":              2,
+				"scheduleTimeoutSeconds": 600,
+			},
+		})
+		return &admissionv1.AdmissionRequest{
+			UID:    "test-uid",
+			Object: runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	// Nothing is required by default.
+	server := NewServer(8443, "", "", slog.Default())
+	response := server.validateJobGroup(server.logger, newRequest(nil))
+	assert.True(t, response.Allowed)
+
+	server = NewServerWithOptions(WithRequiredLabels("team", "cost-center"))
+
+	response = server.validateJobGroup(server.logger, newRequest(map[string]interface{}{
+		"team":        "ml",
+		"cost-center": "cc-42",
+	}))
+	assert.True(t, response.Allowed)
+
+	response = server.validateJobGroup(server.logger, newRequest(map[string]interface{}{"team": "ml"}))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "missing required labels: cost-center", response.Result.Message)
+	assert.Equal(t, "metadata.labels", response.Result.Details.Causes[0].Field)
+
+	response = server.validateJobGroup(server.logger, newRequest(nil))
+	assert.False(t, response.Allowed)
+	assert.Equal(t, "missing required labels: team, cost-center", response.Result.Message)
+}
+
+func TestHandlers_CountRequests(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default(), metrics.WithDisabledMetrics())
+	server := NewServerWithOptions(WithCollector(collector))
+
+	dryRun := true
+	for i, req := range []*admissionv1.AdmissionRequest{
+		{Operation: admissionv1.Create},
+		{Operation: admissionv1.Update, DryRun: &dryRun},
+	} {
+		req.UID = types.UID(fmt.Sprintf("uid-%d", i))
+		body, _ := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request:  req,
+		})
+		server.handleValidate(httptest.NewRecorder(), newJSONRequest("/validate", body))
+	}
+
+	families, err := collector.Gather()
+	require.NoError(t, err)
+	counts := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "volcano_webhook_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			counts[labels["path"]+" "+labels["operation"]+" "+labels["dry_run"]] = metric.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"/validate CREATE false": 1,
+		"/validate UPDATE true":  1,
+	}, counts)
+}
+
+func TestRoutes_MetricsEndpoint(t *testing.T) {
+	collector := metrics.NewCollector(slog.Default(), metrics.WithDisabledMetrics())
+	server := NewServerWithOptions(WithCollector(collector), WithMetricsEndpoint())
+
+	body, _ := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  &admissionv1.AdmissionRequest{UID: "metrics-uid", Operation: admissionv1.Create},
+	})
+	mux := server.routes()
+	mux.ServeHTTP(httptest.NewRecorder(), newJSONRequest("/validate", body))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `volcano_webhook_requests_total{dry_run="false",operation="CREATE",path="/validate"} 1`)
+
+	// Metrics stay off the admission port unless asked for.
+	rec = httptest.NewRecorder()
+	NewServerWithOptions(WithCollector(collector)).routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
 }